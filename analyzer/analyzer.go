@@ -0,0 +1,68 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analyzer provides nestif as an analysis.Analyzer, to run it with
+// go vet, golangci-lint or any other driver of the go/analysis framework.
+package analyzer
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/nakabonne/nestif"
+)
+
+// Analyzer reports complex nested if statements.
+var Analyzer = &analysis.Analyzer{
+	Name:     "nestif",
+	Doc:      "reports complex nested if statements",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// Minimum complexity to report, given by -nestif.min.
+var minComplexity int
+
+func init() {
+	Analyzer.Flags.IntVar(&minComplexity, "min", 1, "minimum complexity to report")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	checker := &nestif.Checker{
+		MinComplexity: minComplexity,
+	}
+	// Functions are found in top-level declarations, which are checked
+	// along with the file they are in.
+	nodeFilter := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.FuncDecl)(nil),
+		(*ast.GenDecl)(nil),
+	}
+	var file *ast.File
+	ins.Nodes(nodeFilter, func(n ast.Node, push bool) bool {
+		if !push {
+			return false
+		}
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			return true
+		}
+		tf := pass.Fset.File(file.Pos())
+		for _, issue := range checker.CheckDecl(file, n.(ast.Decl), pass.Fset) {
+			pass.Report(analysis.Diagnostic{
+				Pos:     tf.Pos(issue.Pos.Offset),
+				End:     tf.Pos(issue.EndPos.Offset),
+				Message: issue.Message,
+			})
+		}
+		return false
+	})
+	return nil, nil
+}
@@ -0,0 +1,22 @@
+package a
+
+func _() {
+	var b1, b2 bool
+
+	if b1 {
+	}
+
+	if b1 { // want "`if b1` has complex nested blocks \\(complexity: 1\\)"
+		if b2 {
+		}
+	}
+}
+
+var _ = func() {
+	var b1, b2 bool
+
+	if b1 { // want "`if b1` has complex nested blocks \\(complexity: 1\\)"
+		if b2 {
+		}
+	}
+}
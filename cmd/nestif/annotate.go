@@ -0,0 +1,64 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/nakabonne/nestif"
+)
+
+// writeAnnotated prints each file containing issues with a complexity comment
+// inserted above every flagged if. Files on disk are left untouched.
+func (a *app) writeAnnotated(issues []nestif.Issue) {
+	byFile := make(map[string][]nestif.Issue)
+	var files []string
+	for _, issue := range issues {
+		name := issue.Pos.Filename
+		if _, ok := byFile[name]; !ok {
+			files = append(files, name)
+		}
+		byFile[name] = append(byFile[name], issue)
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		src, err := ioutil.ReadFile(f)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			continue
+		}
+		fmt.Fprintf(a.stdout, "// %s\n", f)
+		a.stdout.Write(annotate(src, byFile[f]))
+	}
+}
+
+// annotate inserts `// nestif: complexity N` comments above the lines of the given issues,
+// indented the same as the if.
+func annotate(src []byte, issues []nestif.Issue) []byte {
+	complexities := make(map[int]int, len(issues))
+	for _, issue := range issues {
+		complexities[issue.Pos.Line] = issue.Complexity
+	}
+
+	b := new(bytes.Buffer)
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Bytes()
+		if c, ok := complexities[line]; ok {
+			indent := text[:len(text)-len(bytes.TrimLeft(text, " \t"))]
+			fmt.Fprintf(b, "%s// nestif: complexity %d\n", indent, c)
+		}
+		b.Write(text)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
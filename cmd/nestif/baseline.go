@@ -0,0 +1,111 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nakabonne/nestif"
+)
+
+// fingerprint identifies an issue regardless of its line, so that known issues
+// stay known while the code around them changes.
+type fingerprint struct {
+	File      string `json:"file"`
+	Func      string `json:"func"`
+	Condition string `json:"condition"`
+}
+
+// baselineEntry is a fingerprint with the number of issues sharing it,
+// like the same condition checked twice in a function.
+type baselineEntry struct {
+	fingerprint
+	Count int `json:"count"`
+}
+
+// fingerprintOf gives the fingerprint of the issue. The file is relative to
+// the current directory with slashes, however it was given.
+func fingerprintOf(issue nestif.Issue) fingerprint {
+	file := issue.Pos.Filename
+	if abs, err := filepath.Abs(file); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				file = rel
+			}
+		}
+	}
+	return fingerprint{
+		File:      filepath.ToSlash(file),
+		Func:      issue.FuncName,
+		Condition: strings.Join(strings.Fields(issue.Condition), " "),
+	}
+}
+
+// writeBaseline saves the fingerprints of the issues to path, sorted so that
+// the file can be kept under version control.
+func writeBaseline(path string, issues []nestif.Issue) error {
+	counts := make(map[fingerprint]int)
+	for _, issue := range issues {
+		counts[fingerprintOf(issue)]++
+	}
+	entries := make([]baselineEntry, 0, len(counts))
+	for fp, n := range counts {
+		entries = append(entries, baselineEntry{fingerprint: fp, Count: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		p, q := entries[i], entries[j]
+		if p.File != q.File {
+			return p.File < q.File
+		}
+		if p.Func != q.Func {
+			return p.Func < q.Func
+		}
+		return p.Condition < q.Condition
+	})
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// filterBaseline drops the issues known in the baseline at path. As many issues
+// as counted in the baseline are dropped per fingerprint, so that another one
+// added with the same fingerprint is still reported.
+func filterBaseline(path string, issues []nestif.Issue) ([]nestif.Issue, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("baseline %s not found; give --write-baseline to create it", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []baselineEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %v", path, err)
+	}
+	known := make(map[fingerprint]int, len(entries))
+	for _, e := range entries {
+		known[e.fingerprint] += e.Count
+	}
+	filtered := make([]nestif.Issue, 0, len(issues))
+	for _, issue := range issues {
+		fp := fingerprintOf(issue)
+		if known[fp] > 0 {
+			known[fp]--
+			continue
+		}
+		filtered = append(filtered, issue)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nestif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "a.go")
+	baseline := filepath.Join(dir, "baseline.json")
+	run := func(writeBaseline bool) (int, string) {
+		b := new(bytes.Buffer)
+		a := app{
+			minComplexity: 1,
+			top:           10,
+			baseline:      baseline,
+			writeBaseline: writeBaseline,
+			stdout:        b,
+			stderr:        b,
+		}
+		return a.run([]string{src}), b.String()
+	}
+
+	old := "package a\n\nfunc F(b1, b2 bool) {\n\tif b1 {\n\t\tif b2 {\n\t\t}\n\t}\n}\n"
+	if err := ioutil.WriteFile(src, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, out := run(false)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "baseline "+baseline+" not found; give --write-baseline to create it\n", out)
+
+	code, out = run(true)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", out)
+	b, err := ioutil.ReadFile(baseline)
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "[\n  {\n    \"file\": \""+filepath.ToSlash(rel)+"\",\n    \"func\": \"F\",\n    \"condition\": \"b1\",\n    \"count\": 1\n  }\n]\n", string(b))
+
+	// Moving the known issue doesn't resurface it, while a new one is reported.
+	changed := "package a\n\n// F does nothing.\nfunc F(b1, b2 bool) {\n\tif b1 {\n\t\tif b2 {\n\t\t}\n\t}\n\tif b1 {\n\t\tif b2 {\n\t\t}\n\t}\n}\n"
+	if err := ioutil.WriteFile(src, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, out = run(false)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, src+":9:2: `if b1` has complex nested blocks (complexity: 1)\n", out)
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nakabonne/nestif"
+)
+
+// blamer tells who wrote a given line.
+type blamer interface {
+	// Author returns the email address of the last author of the line.
+	Author(filename string, line int) (string, error)
+	// CurrentUser returns the email address of the current user.
+	CurrentUser() (string, error)
+}
+
+// gitBlamer is a blamer backed by the git command.
+type gitBlamer struct{}
+
+func (gitBlamer) Author(filename string, line int) (string, error) {
+	dir, base := filepath.Split(filename)
+	cmd := exec.Command("git", "blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", line, line), "--", base)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run git blame on %s: %v", filename, err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if mail := strings.TrimPrefix(sc.Text(), "author-mail "); mail != sc.Text() {
+			return strings.Trim(mail, "<>"), nil
+		}
+	}
+	return "", fmt.Errorf("no author found for %s:%d", filename, line)
+}
+
+func (gitBlamer) CurrentUser() (string, error) {
+	out, err := exec.Command("git", "config", "user.email").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current git user: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// filterMine returns only issues last authored by the current user.
+// Issues whose author can't be determined, e.g. files not under git, are dropped.
+func (a *app) filterMine(issues []nestif.Issue) ([]nestif.Issue, error) {
+	me, err := a.blamer.CurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	mine := make([]nestif.Issue, 0, len(issues))
+	for _, issue := range issues {
+		author, err := a.blamer.Author(issue.Pos.Filename, issue.Pos.Line)
+		if err != nil {
+			a.debug(err)
+			continue
+		}
+		if author == me {
+			mine = append(mine, issue)
+		}
+	}
+	return mine, nil
+}
+
+// authorStat is the tally of issues attributed to an author.
+type authorStat struct {
+	author     string
+	issues     int
+	complexity int
+}
+
+// writeByAuthor prints the number of issues and the total complexity per author.
+// Issues whose author can't be determined, e.g. files not under git, are
+// attributed to "unknown".
+func (a *app) writeByAuthor(issues []nestif.Issue) {
+	stats := make(map[string]*authorStat)
+	for _, issue := range issues {
+		author, err := a.blamer.Author(issue.Pos.Filename, issue.Pos.Line)
+		if err != nil {
+			a.debug(err)
+			author = "unknown"
+		}
+		s, ok := stats[author]
+		if !ok {
+			s = &authorStat{author: author}
+			stats[author] = s
+		}
+		s.issues++
+		s.complexity += issue.Complexity
+	}
+
+	list := make([]*authorStat, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].complexity != list[j].complexity {
+			return list[i].complexity > list[j].complexity
+		}
+		return list[i].author < list[j].author
+	})
+	for _, s := range list {
+		fmt.Fprintf(a.stdout, "%s: issues: %d, total complexity: %d\n", s.author, s.issues, s.complexity)
+	}
+}
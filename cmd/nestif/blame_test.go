@@ -0,0 +1,117 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBlamer struct {
+	user    string
+	userErr error
+	// authors maps "file:line" to its author.
+	authors map[string]string
+}
+
+func (f *fakeBlamer) Author(filename string, line int) (string, error) {
+	author, ok := f.authors[fmt.Sprintf("%s:%d", filename, line)]
+	if !ok {
+		return "", fmt.Errorf("%s is not under git", filename)
+	}
+	return author, nil
+}
+
+func (f *fakeBlamer) CurrentUser() (string, error) {
+	return f.user, f.userErr
+}
+
+func TestRunMine(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		blamer *fakeBlamer
+		want   string
+		code   int
+	}{
+		{
+			name: "only issues authored by current user",
+			args: []string{"../../testdata/d.go"},
+			blamer: &fakeBlamer{
+				user: "me@example.com",
+				authors: map[string]string{
+					"../../testdata/d.go:6":  "me@example.com",
+					"../../testdata/d.go:11": "other@example.com",
+					"../../testdata/d.go:16": "me@example.com",
+				},
+			},
+			want: "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n../../testdata/d.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code: 0,
+		},
+		{
+			name: "file not under git",
+			args: []string{"../../testdata/a.go"},
+			blamer: &fakeBlamer{
+				user: "me@example.com",
+			},
+			want: "",
+			code: 0,
+		},
+		{
+			name: "current user unknown",
+			args: []string{"../../testdata/a.go"},
+			blamer: &fakeBlamer{
+				userErr: errors.New("failed to get current git user"),
+			},
+			want: "failed to get current git user\n",
+			code: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			a := app{
+				minComplexity: 1,
+				top:           10,
+				mine:          true,
+				blamer:        tc.blamer,
+				stdout:        b,
+				stderr:        b,
+			}
+			c := a.run(tc.args)
+			assert.Equal(t, tc.code, c)
+			assert.Equal(t, tc.want, b.String())
+		})
+	}
+}
+
+func TestRunByAuthor(t *testing.T) {
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		byAuthor:      true,
+		blamer: &fakeBlamer{
+			authors: map[string]string{
+				"../../testdata/d.go:6":  "alice@example.com",
+				"../../testdata/d.go:11": "bob@example.com",
+				"../../testdata/d.go:16": "alice@example.com",
+				"../../testdata/b.go:5":  "bob@example.com",
+			},
+		},
+		stdout: b,
+		stderr: b,
+	}
+	c := a.run([]string{"../../testdata/b.go", "../../testdata/d.go", "../../testdata/a.go"})
+	assert.Equal(t, 0, c)
+	assert.Equal(t, "bob@example.com: issues: 2, total complexity: 10\nalice@example.com: issues: 2, total complexity: 4\nunknown: issues: 1, total complexity: 1\n", b.String())
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"strings"
+
+	"github.com/nakabonne/nestif"
+)
+
+// checkChangedFuncs checks the new version of a file given as "old=new", and
+// returns only issues in the functions that were added or whose source changed.
+func (a *app) checkChangedFuncs(checker *nestif.Checker, spec string) ([]nestif.Issue, error) {
+	i := strings.Index(spec, "=")
+	if i < 0 {
+		return nil, usageErrorf("invalid changed-funcs value %q: must be old=new", spec)
+	}
+	oldPath, newPath := spec[:i], spec[i+1:]
+
+	oldFset := token.NewFileSet()
+	oldFile, err := parseFile(oldFset, oldPath)
+	if err != nil {
+		return nil, err
+	}
+	oldHashes := funcHashes(oldFile, oldFset)
+
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, newPath)
+	if err != nil {
+		return nil, err
+	}
+	var changed []*ast.FuncDecl
+	for key, h := range funcHashes(f, fset) {
+		if oldHashes[key] != h {
+			changed = append(changed, funcDecl(f, key))
+		}
+	}
+
+	var issues []nestif.Issue
+	for _, issue := range checker.Check(f, fset) {
+		for _, fn := range changed {
+			if fset.Position(fn.Pos()).Offset <= issue.Pos.Offset && issue.Pos.Offset < fset.Position(fn.End()).Offset {
+				issues = append(issues, issue)
+				break
+			}
+		}
+	}
+	return issues, nil
+}
+
+func parseFile(fset *token.FileSet, path string) (*ast.File, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseFile(fset, path, src, 0)
+}
+
+// funcHashes gives the hash of the source of each function, keyed by funcKey.
+func funcHashes(f *ast.File, fset *token.FileSet) map[string][sha256.Size]byte {
+	hashes := make(map[string][sha256.Size]byte)
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		b := new(bytes.Buffer)
+		if err := printer.Fprint(b, fset, fn); err != nil {
+			continue
+		}
+		hashes[funcKey(fn)] = sha256.Sum256(b.Bytes())
+	}
+	return hashes
+}
+
+func funcDecl(f *ast.File, key string) *ast.FuncDecl {
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && funcKey(fn) == key {
+			return fn
+		}
+	}
+	return nil
+}
+
+// funcKey identifies a function by its name, prefixed by the receiver type for methods.
+func funcKey(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	b := new(bytes.Buffer)
+	printer.Fprint(b, token.NewFileSet(), fn.Recv.List[0].Type)
+	return b.String() + "." + fn.Name.Name
+}
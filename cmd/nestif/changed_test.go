@@ -0,0 +1,58 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunChangedFuncs(t *testing.T) {
+	cases := []struct {
+		name         string
+		changedFuncs string
+		want         string
+		code         int
+	}{
+		{
+			name:         "changed and added functions",
+			changedFuncs: "../../testdata/changed/old.go=../../testdata/changed/new.go",
+			want:         "../../testdata/changed/new.go:17:2: `if b1` has complex nested blocks (complexity: 3)\n../../testdata/changed/new.go:28:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:         0,
+		},
+		{
+			name:         "nothing changed",
+			changedFuncs: "../../testdata/changed/old.go=../../testdata/changed/old.go",
+			want:         "",
+			code:         0,
+		},
+		{
+			name:         "invalid value",
+			changedFuncs: "../../testdata/changed/new.go",
+			want:         "invalid changed-funcs value \"../../testdata/changed/new.go\": must be old=new\n",
+			code:         2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			a := app{
+				minComplexity: 1,
+				top:           10,
+				changedFuncs:  tc.changedFuncs,
+				stdout:        b,
+				stderr:        b,
+			}
+			c := a.run(nil)
+			assert.Equal(t, tc.code, c)
+			assert.Equal(t, tc.want, b.String())
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"sort"
+
+	"github.com/nakabonne/nestif"
+)
+
+type checkstyleOutput struct {
+	XMLName xml.Name          `xml:"checkstyle"`
+	Version string            `xml:"version,attr"`
+	Files   []*checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleReport converts issues into a checkstyle XML document, grouping them by file.
+func checkstyleReport(issues []nestif.Issue) ([]byte, error) {
+	out := checkstyleOutput{Version: "5.0"}
+	files := make(map[string]*checkstyleFile)
+	for _, issue := range issues {
+		name := issue.Pos.Filename
+		f, ok := files[name]
+		if !ok {
+			f = &checkstyleFile{Name: name}
+			files[name] = f
+			out.Files = append(out.Files, f)
+		}
+		f.Errors = append(f.Errors, checkstyleError{
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+			Severity: checkstyleSeverity(issue.Severity),
+			Message:  issue.Message,
+			Source:   "nestif",
+		})
+	}
+	sort.Slice(out.Files, func(i, j int) bool {
+		return out.Files[i].Name < out.Files[j].Name
+	})
+	b, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// checkstyleSeverity maps the severity of an issue to a checkstyle one,
+// which shares the names.
+func checkstyleSeverity(severity string) string {
+	if severity == "" {
+		return "warning"
+	}
+	return severity
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultConfigFiles are looked up in the current directory unless --config is given.
+var defaultConfigFiles = []string{".nestif.yml", ".nestif.yaml"}
+
+// config is the content of a configuration file.
+type config struct {
+	Min          *int     `yaml:"min"`
+	Top          *int     `yaml:"top"`
+	ExcludeDirs  []string `yaml:"exclude-dirs"`
+	ExcludeFiles []string `yaml:"exclude-files"`
+}
+
+// loadConfig applies the configuration file at path to the settings not given
+// by the flags, which changed tells. If path is empty, the default files in
+// the current directory are used if any.
+func (a *app) loadConfig(path string, changed func(flag string) bool) error {
+	if path == "" {
+		for _, f := range defaultConfigFiles {
+			if exists(f) {
+				path = f
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var c config
+	// yaml.v2 is used rather than yaml.v3, since it's the version the module
+	// already depends on. Unknown keys are rejected, as KnownFields would in v3.
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	if c.Min != nil && !changed("min") {
+		a.mins = []string{strconv.Itoa(*c.Min)}
+	}
+	if c.Top != nil && !changed("top") {
+		a.top = *c.Top
+	}
+	if c.ExcludeDirs != nil && !changed("exclude-dirs") {
+		a.excludeDirs = c.ExcludeDirs
+	}
+	if c.ExcludeFiles != nil && !changed("exclude-files") {
+		a.excludeFiles = c.ExcludeFiles
+	}
+	return nil
+}
+
+// flagChanged tells whether the flag was given on the command line.
+func flagChanged(name string) bool {
+	f := flagSet.Lookup(name)
+	return f != nil && f.Changed
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		dir     string
+		path    string
+		changed map[string]bool
+		want    app
+		wantErr bool
+	}{
+		{
+			name: "default file in current directory",
+			dir:  "../../testdata/config",
+			want: app{
+				mins:        []string{"4"},
+				top:         5,
+				excludeDirs: []string{"vendor", "third_party"},
+			},
+		},
+		{
+			name:    "flags take precedence",
+			dir:     "../../testdata/config",
+			changed: map[string]bool{"min": true, "exclude-dirs": true},
+			want: app{
+				mins: []string{"1"},
+				top:  5,
+			},
+		},
+		{
+			name: "file given",
+			path: "../../testdata/config/other.yaml",
+			want: app{
+				mins:         []string{"1"},
+				top:          10,
+				excludeFiles: []string{`_gen\.go$`},
+			},
+		},
+		{
+			name: "no default file",
+			want: app{
+				mins: []string{"1"},
+				top:  10,
+			},
+		},
+		{
+			name:    "file not found",
+			path:    "../../testdata/config/not-found.yml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid file",
+			path:    "../../testdata/config/invalid.yml",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.dir != "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chdir(tc.dir); err != nil {
+					t.Fatal(err)
+				}
+				defer os.Chdir(wd)
+			}
+			a := app{
+				mins: []string{"1"},
+				top:  10,
+			}
+			err := a.loadConfig(tc.path, func(flag string) bool { return tc.changed[flag] })
+			assert.Equal(t, tc.wantErr, err != nil)
+			if tc.wantErr {
+				return
+			}
+			assert.Equal(t, tc.want, a)
+		})
+	}
+}
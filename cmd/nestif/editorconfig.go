@@ -0,0 +1,208 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nakabonne/nestif"
+)
+
+// expandColumns rewrites the columns of the issues as if tabs were expanded
+// to the tab width set for each file in the nearest .editorconfig files.
+// Files without a tab width configured are left as they are.
+func (a *app) expandColumns(issues []nestif.Issue) {
+	widths := make(map[string]int)
+	lines := make(map[string][][]byte)
+	for i := range issues {
+		name := issues[i].Pos.Filename
+		width, ok := widths[name]
+		if !ok {
+			width = tabWidth(name)
+			widths[name] = width
+		}
+		if width <= 0 {
+			continue
+		}
+		ls, ok := lines[name]
+		if !ok {
+			src, err := ioutil.ReadFile(name)
+			if err != nil {
+				a.debug(err)
+			}
+			ls = bytes.Split(src, []byte("\n"))
+			lines[name] = ls
+		}
+		expandColumn(&issues[i].Pos.Column, ls, issues[i].Pos.Line, width)
+		expandColumn(&issues[i].EndPos.Column, ls, issues[i].EndPos.Line, width)
+	}
+}
+
+// expandColumn converts the byte column on the given line into the column
+// seen with tabs expanded to width.
+func expandColumn(column *int, lines [][]byte, line, width int) {
+	if line < 1 || line > len(lines) || *column < 1 {
+		return
+	}
+	l := lines[line-1]
+	if *column-1 > len(l) {
+		return
+	}
+	var col int
+	for _, b := range l[:*column-1] {
+		if b == '\t' {
+			col += width - col%width
+		} else {
+			col++
+		}
+	}
+	*column = col + 1
+}
+
+// tabWidth returns the tab width set for the file in the .editorconfig files
+// found from its directory up to the root, or 0 if there is none.
+// As specified by EditorConfig, closer files take precedence, and tab_width
+// defaults to indent_size.
+func tabWidth(filename string) int {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return 0
+	}
+	// Properties of each .editorconfig, from the closest one.
+	var configs []map[string]string
+	for dir := filepath.Dir(path); ; {
+		if props, err := parseEditorConfig(filepath.Join(dir, ".editorconfig"), path); err == nil {
+			configs = append(configs, props)
+			if props["root"] == "true" {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := make(map[string]string)
+	for i := len(configs) - 1; i >= 0; i-- {
+		for k, v := range configs[i] {
+			props[k] = v
+		}
+	}
+	for _, key := range []string{"tab_width", "indent_size"} {
+		if w, err := strconv.Atoi(props[key]); err == nil && w > 0 {
+			return w
+		}
+	}
+	return 0
+}
+
+// parseEditorConfig returns the properties in the .editorconfig at config
+// that apply to the file at path, along with "root" from the preamble.
+func parseEditorConfig(config, path string) (map[string]string, error) {
+	f, err := os.Open(config)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	props := make(map[string]string)
+	preamble, match := true, false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			preamble = false
+			match = matchSection(line[1:len(line)-1], filepath.Dir(config), path)
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.ToLower(strings.TrimSpace(line[i+1:]))
+		if (preamble && key == "root") || match {
+			props[key] = value
+		}
+	}
+	return props, s.Err()
+}
+
+// matchSection reports whether the section glob of the .editorconfig in dir
+// matches the file at path. Globs without a slash match the base name
+// at any depth.
+func matchSection(glob, dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	glob = strings.TrimPrefix(glob, "/")
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	re, err := regexp.Compile("^" + globToRegexp(glob) + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(rel)
+}
+
+// globToRegexp converts an EditorConfig glob into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	var braces int
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				b.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '{':
+			braces++
+			b.WriteString("(")
+		case '}':
+			if braces > 0 {
+				braces--
+				b.WriteString(")")
+			} else {
+				b.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '[', ']':
+			b.WriteByte(c)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
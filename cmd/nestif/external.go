@@ -0,0 +1,75 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isExternal reports whether dir is outside the main module, that is,
+// under GOROOT, under the module cache, or in a directory belonging to
+// another module. The main module is the one containing the working directory.
+func isExternal(dir string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if within(dir, filepath.Join(build.Default.GOROOT, "src")) || within(dir, modCache()) {
+		return true
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	root := moduleRoot(wd)
+	if root == "" {
+		return false
+	}
+	return moduleRoot(dir) != root
+}
+
+// moduleRoot returns the nearest directory at or above dir holding a go.mod file.
+// It returns "" if there is none.
+func moduleRoot(dir string) string {
+	for {
+		if exists(filepath.Join(dir, "go.mod")) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// modCache returns the module cache directory, honoring GOMODCACHE.
+func modCache() string {
+	if d := os.Getenv("GOMODCACHE"); d != "" {
+		return d
+	}
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// within reports whether path is root itself or lies under it.
+func within(path, root string) bool {
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
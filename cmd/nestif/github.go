@@ -0,0 +1,34 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nakabonne/nestif"
+)
+
+var (
+	// Escapes for the data of GitHub Actions workflow commands.
+	githubDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	// Escapes for the property values, which additionally can't contain ':' and ','.
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// writeGitHubActions prints each issue as a workflow command, so that it's
+// shown as an annotation in GitHub Actions.
+func (a *app) writeGitHubActions(issues []nestif.Issue) {
+	for _, issue := range issues {
+		fmt.Fprintf(a.stdout, "::warning file=%s,line=%d,col=%d::%s\n",
+			githubPropertyEscaper.Replace(issue.Pos.Filename),
+			issue.Pos.Line,
+			issue.Pos.Column,
+			githubDataEscaper.Replace(issue.Message),
+		)
+	}
+}
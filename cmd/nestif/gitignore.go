@@ -0,0 +1,191 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignore decides whether directories are ignored by the .gitignore files
+// of the repository they are in. It covers the usual syntax: comments,
+// negation with "!", patterns anchored by a "/" in them, and "**" matching
+// any number of directories.
+type gitignore struct {
+	// Root of the repository, or of the walk if it's not in a repository.
+	root  string
+	rules []ignoreRule
+	// Directories whose .gitignore has been read.
+	loaded map[string]bool
+}
+
+type ignoreRule struct {
+	// Directory of the .gitignore, relative to the root in slash form.
+	// Empty for the root itself.
+	base     string
+	pattern  string
+	negate   bool
+	anchored bool
+}
+
+// newGitignore returns a gitignore for walking from dir, reading .gitignore
+// files from the root of the repository containing dir.
+func newGitignore(dir string) (*gitignore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root := abs
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return &gitignore{
+		root:   root,
+		loaded: make(map[string]bool),
+	}, nil
+}
+
+// ignored reports whether the directory at the given path is ignored. Rules
+// of deeper .gitignore files take precedence, and the last matching rule wins.
+func (g *gitignore) ignored(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(g.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// Read the .gitignore of every directory above this one, from the root.
+	g.load("")
+	for i := range rel {
+		if rel[i] == '/' {
+			g.load(rel[:i])
+		}
+	}
+
+	ignored := false
+	for _, r := range g.rules {
+		if r.matches(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// ignoredBelow reports whether the directory, or any directory between root
+// and it, is ignored. Both are absolute paths.
+func (g *gitignore) ignoredBelow(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return false
+	}
+	d := root
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		d = filepath.Join(d, elem)
+		if g.ignored(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the rules of the .gitignore in the directory given relative to the root.
+func (g *gitignore) load(base string) {
+	if g.loaded[base] {
+		return
+	}
+	g.loaded[base] = true
+	f, err := os.Open(filepath.Join(g.root, filepath.FromSlash(base), ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if r, ok := parseIgnoreRule(base, s.Text()); ok {
+			g.rules = append(g.rules, r)
+		}
+	}
+}
+
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	// Only directories are walked, so a trailing slash changes nothing.
+	line = strings.TrimRight(line, "/")
+	// A slash at the beginning or in the middle anchors the pattern to the base.
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// matches reports whether the rule matches the directory given relative to
+// the root.
+func (r ignoreRule) matches(rel string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if !r.anchored {
+		// Parents have been matched on their way down, so only the last
+		// element is left to see.
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches the path elements against the pattern elements,
+// where "**" matches zero or more elements.
+func matchSegments(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchSegments(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], elems[1:])
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreRuleMatches(t *testing.T) {
+	cases := []struct {
+		name string
+		base string
+		line string
+		rel  string
+		want bool
+	}{
+		{
+			name: "name at any level",
+			line: "vendor",
+			rel:  "a/b/vendor",
+			want: true,
+		},
+		{
+			name: "glob",
+			line: "*.cache",
+			rel:  "a/go.cache",
+			want: true,
+		},
+		{
+			name: "trailing slash",
+			line: "build/",
+			rel:  "a/build",
+			want: true,
+		},
+		{
+			name: "anchored at root",
+			line: "/build",
+			rel:  "a/build",
+			want: false,
+		},
+		{
+			name: "anchored by middle slash",
+			line: "a/build",
+			rel:  "a/build",
+			want: true,
+		},
+		{
+			name: "double star",
+			line: "**/gen",
+			rel:  "a/b/gen",
+			want: true,
+		},
+		{
+			name: "nested gitignore",
+			base: "a",
+			line: "/gen",
+			rel:  "a/gen",
+			want: true,
+		},
+		{
+			name: "nested gitignore outside its directory",
+			base: "a",
+			line: "gen",
+			rel:  "b/gen",
+			want: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ok := parseIgnoreRule(tc.base, tc.line)
+			if !ok {
+				t.Fatalf("failed to parse %q", tc.line)
+			}
+			assert.Equal(t, tc.want, r.matches(tc.rel))
+		})
+	}
+}
+
+func TestAllPackagesInFSRespectGitignore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nestif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		".gitignore":          "vendor/\n/build\ngen*\n!generous\n",
+		"a.go":                "package p\n",
+		"build/a.go":          "package build\n",
+		"sub/build/a.go":      "package build\n",
+		"sub/.gitignore":      "local\n",
+		"sub/local/a.go":      "package local\n",
+		"vendor/x/a.go":       "package x\n",
+		"generated/a.go":      "package generated\n",
+		"generous/a.go":       "package generous\n",
+		"other/local/a.go":    "package local\n",
+		"other/vendor/y/a.go": "package y\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	b := new(bytes.Buffer)
+	a := app{respectGitignore: true, stdout: b, stderr: b}
+	assert.ElementsMatch(t, []string{"./.", "./generous", "./other/local", "./sub/build"}, dirsOf(a.allPackagesInFS("./...")))
+	assert.Empty(t, b.String())
+
+	// Vendor directories are never matched by "...".
+	a.respectGitignore = false
+	assert.Len(t, a.allPackagesInFS("./..."), 7)
+}
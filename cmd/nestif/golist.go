@@ -0,0 +1,49 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nakabonne/nestif"
+)
+
+// goListPackage holds the fields of `go list -json` output needed for checking.
+type goListPackage struct {
+	Dir         string
+	GoFiles     []string
+	CgoFiles    []string
+	TestGoFiles []string
+	Error       *struct {
+		Err string
+	}
+}
+
+// checkGoList checks the packages read from a stream of `go list -json` output.
+func (a *app) checkGoList(checker *nestif.Checker, r io.Reader) ([]nestif.Issue, error) {
+	var issues []nestif.Issue
+	dec := json.NewDecoder(r)
+	for {
+		var p goListPackage
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %v", err)
+		}
+		if p.Error != nil {
+			a.debug(fmt.Errorf("%s", p.Error.Err))
+		}
+		var files []string
+		files = append(files, p.GoFiles...)
+		files = append(files, p.CgoFiles...)
+		files = append(files, p.TestGoFiles...)
+		issues = append(issues, a.checkPkgDir(checker, pkgDir{Dir: p.Dir, Files: files})...)
+	}
+	return issues, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunGoList(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+		code  int
+	}{
+		{
+			name: "multiple packages",
+			input: `{
+	"Dir": "../../testdata/a",
+	"ImportPath": "github.com/nakabonne/nestif/testdata/a",
+	"GoFiles": ["a.go"]
+}
+{
+	"Dir": "../../testdata/a/b",
+	"ImportPath": "github.com/nakabonne/nestif/testdata/a/b",
+	"GoFiles": ["a.go"]
+}
+`,
+			want: "../../testdata/a/a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/a/b/a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code: 0,
+		},
+		{
+			name:  "empty stream",
+			input: "",
+			want:  "",
+			code:  0,
+		},
+		{
+			name:  "broken stream",
+			input: `{"Dir": `,
+			want:  "failed to decode go list output: unexpected EOF\n",
+			code:  3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			a := app{
+				minComplexity: 1,
+				top:           10,
+				goList:        true,
+				stdin:         strings.NewReader(tc.input),
+				stdout:        b,
+				stderr:        b,
+			}
+			c := a.run(nil)
+			assert.Equal(t, tc.code, c)
+			assert.Equal(t, tc.want, b.String())
+		})
+	}
+}
@@ -4,122 +4,69 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Copyright (c) 2013 The Go Authors. All rights reserved.
-//
-// Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file or at
-// https://developers.google.com/open-source/licenses/bsd.
-
 package main
 
-/*
-
-This file holds a direct copy of the import path matching code of
-https://github.com/golang/go/blob/master/src/cmd/go/main.go. It can be
-replaced when https://golang.org/issue/8768 is resolved.
-
-It has been updated to follow upstream changes in a few ways.
-
-*/
-
-// Require use of the correct import path only for Go 1.12+ users, so
-// any breakages coincide with people updating their CI configs or
-// whatnot.
-
 import (
 	"fmt"
-	"go/build"
-	"io"
-	"log"
-	"os"
-	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
-// matchPattern(pattern)(name) reports whether
-// name matches pattern.  Pattern is a limited glob
-// pattern in which '...' means 'any string' and there
-// is no other special syntax.
-func matchPattern(pattern string) func(name string) bool {
-	re := regexp.QuoteMeta(pattern)
-	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
-	// Special case: foo/... matches foo too.
-	if strings.HasSuffix(re, `/.*`) {
-		re = re[:len(re)-len(`/.*`)] + `(/.*)?`
-	}
-	reg := regexp.MustCompile(`^` + re + `$`)
-	return func(name string) bool {
-		return reg.MatchString(name)
+// allPackagesInFS gives the packages matching a pattern beginning with a
+// directory and ending with "/...", like "./..." or "../foo/...". The
+// directories are joined to the given one, and begin with ./ if the pattern
+// does, so that the file paths of issues read like the arguments.
+// Directories ignored by .gitignore are skipped too with --respect-gitignore.
+func (a *app) allPackagesInFS(pattern string) []pkgDir {
+	root := strings.TrimSuffix(pattern, "/...")
+	pkgs, err := a.loadPackages(root, "./...")
+	if err != nil {
+		a.debug(err)
+		return nil
 	}
-}
-
-// allPackagesInFS is like allPackages but is passed a pattern
-// beginning ./ or ../, meaning it should scan the tree rooted
-// at the given directory.  There are ... in the pattern too.
-func allPackagesInFS(pattern string, w io.Writer) []string {
-	pkgs := matchPackagesInFS(pattern)
-	if len(pkgs) == 0 {
-		fmt.Fprintf(w, "warning: %q matched no packages\n", pattern)
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		a.debug(err)
+		return nil
 	}
-	return pkgs
-}
-
-func matchPackagesInFS(pattern string) []string {
-	// Find directory to begin the scan.
-	// Could be smarter but this one optimization
-	// is enough for now, since ... is usually at the
-	// end of a path.
-	i := strings.Index(pattern, "...")
-	dir, _ := path.Split(pattern[:i])
-
-	// pattern begins with ./ or ../.
-	// path.Clean will discard the ./ but not the ../.
-	// We need to preserve the ./ for pattern matching
-	// and in the returned import paths.
-	prefix := ""
-	if strings.HasPrefix(pattern, "./") {
-		prefix = "./"
+	var ignore *gitignore
+	if a.respectGitignore {
+		if ignore, err = newGitignore(root); err != nil {
+			a.debug(err)
+		}
 	}
-	match := matchPattern(pattern)
 
-	var pkgs []string
-	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
-		if err != nil || !fi.IsDir() {
-			return nil
+	var dirs []pkgDir
+	for _, d := range pkgDirs(pkgs, func(dir string) string { return dir }) {
+		rel, err := filepath.Rel(absRoot, d.Dir)
+		if err != nil {
+			continue
 		}
-		if path == dir {
-			// filepath.Walk starts at dir and recurses. For the recursive case,
-			// the path is the result of filepath.Join, which calls filepath.Clean.
-			// The initial case is not Cleaned, though, so we do this explicitly.
-			//
-			// This converts a path like "./io/" to "io". Without this step, running
-			// "cd $GOROOT/src/pkg; go list ./io/..." would incorrectly skip the io
-			// package, because prepending the prefix "./" to the unclean path would
-			// result in "././io", and match("././io") returns false.
-			path = filepath.Clean(path)
+		if ignore != nil && ignore.ignoredBelow(absRoot, d.Dir) {
+			continue
 		}
-
-		// Avoid .foo, _foo, and testdata directory trees, but do not avoid "." or "..".
-		_, elem := filepath.Split(path)
-		dot := strings.HasPrefix(elem, ".") && elem != "." && elem != ".."
-		if dot || strings.HasPrefix(elem, "_") || elem == "testdata" {
-			return filepath.SkipDir
+		d.Dir = filepath.Join(root, rel)
+		if strings.HasPrefix(pattern, "./") {
+			d.Dir = "./" + filepath.ToSlash(d.Dir)
 		}
+		dirs = append(dirs, d)
+	}
+	if len(dirs) == 0 {
+		fmt.Fprintf(a.notices(), "warning: %q matched no packages\n", pattern)
+	}
+	return dirs
+}
 
-		name := prefix + filepath.ToSlash(path)
-		if !match(name) {
-			return nil
-		}
-		if _, err = build.ImportDir(path, 0); err != nil {
-			if _, noGo := err.(*build.NoGoError); !noGo {
-				log.Print(err)
-			}
-			return nil
-		}
-		pkgs = append(pkgs, name)
-		return nil
-	})
-	return pkgs
+// importPathPackages gives the packages matching the import path pattern,
+// like "github.com/foo/bar" or "github.com/foo/bar/...", by their absolute directories.
+func (a *app) importPathPackages(pattern string) ([]pkgDir, error) {
+	pkgs, err := a.loadPackages("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	dirs := pkgDirs(pkgs, func(dir string) string { return dir })
+	if len(dirs) == 0 && strings.Contains(pattern, "...") {
+		fmt.Fprintf(a.notices(), "warning: %q matched no packages\n", pattern)
+	}
+	return dirs, nil
 }
@@ -8,6 +8,7 @@ package main
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,7 +29,7 @@ func TestAllPackagesInFS(t *testing.T) {
 		{
 			name:    "parent directly",
 			pattern: "../../...",
-			want:    []string{"../..", "../../cmd/nestif"},
+			want:    []string{"../..", "../../analyzer", "../../cmd/nestif"},
 		},
 		{
 			name:    "... glob operator",
@@ -41,9 +42,63 @@ func TestAllPackagesInFS(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			b := new(bytes.Buffer)
-			s := allPackagesInFS(tc.pattern, b)
-			assert.ElementsMatch(t, tc.want, s)
+			a := app{stdout: b, stderr: b}
+			assert.ElementsMatch(t, tc.want, dirsOf(a.allPackagesInFS(tc.pattern)))
 			assert.Equal(t, tc.log, b.String())
 		})
 	}
 }
+
+func TestImportPathPackages(t *testing.T) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		pattern string
+		want    []string
+		log     string
+	}{
+		{
+			name:    "module root",
+			pattern: "github.com/nakabonne/nestif/...",
+			want:    []string{root, filepath.Join(root, "analyzer"), filepath.Join(root, "cmd", "nestif")},
+		},
+		{
+			name:    "directory without go files",
+			pattern: "github.com/nakabonne/nestif/cmd/...",
+			want:    []string{filepath.Join(root, "cmd", "nestif")},
+		},
+		{
+			name:    "wildcard in the middle",
+			pattern: "github.com/nakabonne/nestif/.../nestif",
+			want:    []string{filepath.Join(root, "cmd", "nestif")},
+		},
+		{
+			name:    "no packages",
+			pattern: "github.com/nakabonne/nestif/nope/...",
+			want:    nil,
+			log:     "warning: \"github.com/nakabonne/nestif/nope/...\" matched no packages\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			a := app{stdout: b, stderr: b}
+			dirs, err := a.importPathPackages(tc.pattern)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.want, dirsOf(dirs))
+			assert.Equal(t, tc.log, b.String())
+		})
+	}
+}
+
+func dirsOf(pkgs []pkgDir) []string {
+	var dirs []string
+	for _, p := range pkgs {
+		dirs = append(dirs, p.Dir)
+	}
+	return dirs
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/nakabonne/nestif"
+)
+
+// index is an on-disk cache of the results of files keyed by the hash of their
+// content, so that unchanged files are skipped across runs.
+type index struct {
+	// Options the results were found with. The entries are discarded when they differ.
+	Options string
+	Entries map[string]indexEntry
+
+	// Entries used or added in this run, which are the only ones saved.
+	used map[string]indexEntry
+}
+
+type indexEntry struct {
+	Issues []nestif.Issue
+	Funcs  []nestif.FuncComplexity
+}
+
+// loadIndex reads the index at path, or returns an empty one if there is no file yet.
+func loadIndex(path string, options string) (*index, error) {
+	idx := &index{
+		Options: options,
+		Entries: make(map[string]indexEntry),
+		used:    make(map[string]indexEntry),
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved index
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %v", path, err)
+	}
+	if saved.Options == options && saved.Entries != nil {
+		idx.Entries = saved.Entries
+	}
+	return idx, nil
+}
+
+// indexOptions returns the options that the results depend on.
+func (a *app) indexOptions(checker *nestif.Checker) (string, error) {
+	b, err := json.Marshal(struct {
+		Checker          *nestif.Checker
+		IncludeGenerated bool
+		OnlyGenerated    bool
+	}{checker, a.includeGenerated, a.onlyGenerated})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// lookup returns the results of the source found before, with the positions
+// moved to the given path.
+func (idx *index) lookup(path string, src []byte) (indexEntry, bool) {
+	key := contentHash(src)
+	e, ok := idx.Entries[key]
+	if !ok {
+		return indexEntry{}, false
+	}
+	idx.used[key] = e
+	moved := indexEntry{
+		Issues: make([]nestif.Issue, len(e.Issues)),
+		Funcs:  make([]nestif.FuncComplexity, len(e.Funcs)),
+	}
+	copy(moved.Issues, e.Issues)
+	copy(moved.Funcs, e.Funcs)
+	for i := range moved.Issues {
+		moved.Issues[i].Pos.Filename = path
+		moved.Issues[i].EndPos.Filename = path
+	}
+	for i := range moved.Funcs {
+		moved.Funcs[i].Pos.Filename = path
+	}
+	return moved, true
+}
+
+// store records the results of the source.
+func (idx *index) store(src []byte, e indexEntry) {
+	key := contentHash(src)
+	idx.Entries[key] = e
+	idx.used[key] = e
+}
+
+// save writes the entries used in this run to path.
+func (idx *index) save(path string) error {
+	b, err := json.Marshal(index{Options: idx.Options, Entries: idx.used})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+func contentHash(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
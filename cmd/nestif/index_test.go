@@ -0,0 +1,62 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nestif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.json")
+
+	run := func(args ...string) string {
+		b := new(bytes.Buffer)
+		a := app{
+			minComplexity: 1,
+			top:           10,
+			indexPath:     path,
+			stdout:        b,
+			stderr:        b,
+		}
+		assert.Equal(t, 0, a.run(args))
+		return b.String()
+	}
+
+	want := "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n"
+	assert.Equal(t, want, run("../../testdata/a.go"))
+
+	// Tamper with the stored result to tell whether the second run reuses it.
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.Replace(b, []byte("has complex nested blocks"), []byte("was found in the index"), 1)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "../../testdata/a.go:9:2: `if b1` was found in the index (complexity: 1)\n", run("../../testdata/a.go"))
+
+	// Entries not used in the last run are dropped.
+	run("../../testdata/d.go")
+	b, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, bytes.Contains(b, []byte("../../testdata/a.go")))
+	assert.True(t, bytes.Contains(b, []byte("../../testdata/d.go")))
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/nakabonne/nestif"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Failure *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitReport converts issues into a JUnit XML document where each issue is a failed test case.
+func junitReport(issues []nestif.Issue) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      "nestif",
+		Tests:     len(issues),
+		Failures:  len(issues),
+		TestCases: make([]junitTestCase, 0, len(issues)),
+	}
+	for _, issue := range issues {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:    fmt.Sprintf("%s:%d", issue.Pos.Filename, issue.Pos.Line),
+			Failure: &junitFailure{Message: issue.Message},
+		})
+	}
+	b, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+
+	"github.com/nakabonne/nestif"
+)
+
+// lspPublishDiagnosticsParams is the parameter of the LSP textDocument/publishDiagnostics notification.
+// See: https://microsoft.github.io/language-server-protocol/specifications/specification-current/#textDocument_publishDiagnostics
+type lspPublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspPosition is 0-based, unlike token.Position.
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspSeverities maps the severities of issues to LSP DiagnosticSeverity values.
+var lspSeverities = map[string]int{
+	"error":   1,
+	"warning": 2,
+	"info":    3,
+}
+
+// lspJSON converts issues into the publishDiagnostics parameters of each file.
+// Files are identified by file URIs of their absolute paths.
+func lspJSON(issues []nestif.Issue) ([]byte, error) {
+	diagnostics := make(map[string][]lspDiagnostic)
+	var files []string
+	for _, issue := range issues {
+		name := issue.Pos.Filename
+		if _, ok := diagnostics[name]; !ok {
+			files = append(files, name)
+		}
+		diagnostics[name] = append(diagnostics[name], lspDiagnostic{
+			Range: lspRange{
+				Start: lspPosition{Line: issue.Pos.Line - 1, Character: issue.Pos.Column - 1},
+				End:   lspPosition{Line: issue.EndPos.Line - 1, Character: issue.EndPos.Column - 1},
+			},
+			Severity: lspSeverities[issue.Severity],
+			Code:     ruleNestedIf,
+			Source:   "nestif",
+			Message:  issue.Message,
+		})
+	}
+	sort.Strings(files)
+
+	params := make([]lspPublishDiagnosticsParams, 0, len(files))
+	for _, f := range files {
+		uri := f
+		if abs, err := filepath.Abs(f); err == nil {
+			uri = "file://" + filepath.ToSlash(abs)
+		}
+		params = append(params, lspPublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics[f]})
+	}
+	return json.Marshal(params)
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLSP(t *testing.T) {
+	uri := func(name string) string {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "file://" + filepath.ToSlash(abs)
+	}
+
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		outLSP:        true,
+		stdout:        b,
+		stderr:        b,
+	}
+	c := a.run([]string{"../../testdata/b.go", "../../testdata/a.go"})
+	assert.Equal(t, 0, c)
+	want := fmt.Sprintf(`[{"uri":%q,"diagnostics":[{"range":{"start":{"line":8,"character":1},"end":{"line":11,"character":2}},"severity":2,"code":"nested-if","source":"nestif","message":"`+"`if b1` has complex nested blocks (complexity: 1)"+`"}]},`+
+		`{"uri":%q,"diagnostics":[{"range":{"start":{"line":4,"character":1},"end":{"line":16,"character":2}},"severity":2,"code":"nested-if","source":"nestif","message":"`+"`if b1` has complex nested blocks (complexity: 9)"+`"}]}]`+"\n",
+		uri("../../testdata/a.go"), uri("../../testdata/b.go"))
+	assert.Equal(t, want, b.String())
+}
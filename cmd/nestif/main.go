@@ -7,20 +7,19 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"go/build"
-	"go/parser"
 	"go/token"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/nakabonne/nestif"
 	flag "github.com/spf13/pflag"
@@ -30,7 +29,7 @@ var (
 	flagSet = flag.NewFlagSet("nestif", flag.ContinueOnError)
 
 	usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: nestif [<flag> ...] <Go files or directories or packages> ...")
+		fmt.Fprintln(os.Stderr, "usage: nestif [<flag> ...] <Go files or directories or packages or - for stdin> ...")
 		flagSet.PrintDefaults()
 	}
 
@@ -40,49 +39,570 @@ var (
 )
 
 type app struct {
-	verbose         bool
-	outJSON         bool
-	minComplexity   int
-	top             int
-	excludeDirs     []string
-	excludePatterns []*regexp.Regexp
-	stdout          io.Writer
-	stderr          io.Writer
+	verbose       bool
+	quiet         bool
+	output        string
+	cpuProfile    string
+	memProfile    string
+	outJSON       bool
+	outJSONL      bool
+	outJUnit      bool
+	outSonar      bool
+	outCheckstyle bool
+	outSARIF      bool
+	outGitHub     bool
+	outLSP        bool
+	annotate      bool
+	byAuthor      bool
+	byDir         bool
+	priority      bool
+	brief         bool
+	topo          bool
+	flatness      bool
+	offsets       bool
+	editorConfig  bool
+	showSource    bool
+	sources       map[string][]byte
+	parsed        map[string]*parsedFile
+	// Times each file is left to be checked, to know which to keep parsed.
+	refs map[string]int
+	// Import IDs of the loaded packages by their directories, and their directories by ID.
+	pkgImports       map[string][]string
+	dirByID          map[string]string
+	relative         bool
+	absolute         bool
+	benchfmt         bool
+	histogram        bool
+	table            bool
+	format           string
+	summary          bool
+	checkOnly        bool
+	listFiles        bool
+	baseline         string
+	writeBaseline    bool
+	failOnIssues     bool
+	failOver         int
+	warnComplexity   int
+	errorComplexity  int
+	minComplexity    int
+	mins             []string
+	failLevels       []string
+	maxComplexity    int
+	minLines         int
+	reportAt         string
+	sortBy           string
+	countNegations   bool
+	ignoreEmpty      bool
+	includeSwitch    bool
+	includeLoops     bool
+	includeSelect    bool
+	keepClosures     bool
+	booleanOps       bool
+	ifErr            bool
+	elseIfPenalty    int
+	maxElseIfChain   int
+	initPenalty      int
+	includeLabels    bool
+	exportedOnly     bool
+	elseWeight       *int
+	depthWeight      float64
+	top              int
+	topPerFile       int
+	maxFileSize      int64
+	includeGenerated bool
+	onlyGenerated    bool
+	maxAvg           float64
+	excludeDirs      []string
+	excludePatterns  []*regexp.Regexp
+	respectGitignore bool
+	excludeFiles     []string
+	excludeFilePats  []*regexp.Regexp
+	excludeConds     []string
+	funcs            []nestif.FuncComplexity
+	mine             bool
+	goList           bool
+	skipExternal     bool
+	buildTags        []string
+	changedFuncs     string
+	indexPath        string
+	index            *index
+	blamer           blamer
+	churner          churner
+	stdin            io.Reader
+	stdinPath        string
+	stdout           io.Writer
+	stderr           io.Writer
+
+	// Numbers of files that failed to parse and packages that failed to load.
+	parseErrors int
+	loadErrors  int
 }
 
 func main() {
 	a := &app{
-		stdout: os.Stdout,
-		stderr: os.Stderr,
+		blamer:  gitBlamer{},
+		churner: gitChurner{},
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
 	}
 	flagSet.BoolVarP(&a.verbose, "verbose", "v", false, "verbose output")
+	flagSet.BoolVarP(&a.quiet, "quiet", "q", false, "print only issues and errors, without warnings or verbose output")
+	flagSet.StringVarP(&a.output, "output", "o", "", "write the report to the given file instead of stdout")
+	flagSet.StringVar(&a.format, "format", "text", "output format: text, or the name of any output format flag like json, table or github-actions")
 	flagSet.BoolVar(&a.outJSON, "json", false, "emit json format")
-	flagSet.IntVar(&a.minComplexity, "min", 1, "minimum complexity to show")
-	flagSet.IntVar(&a.top, "top", 10, "show only the top N most complex if statements")
+	flagSet.BoolVar(&a.outJSONL, "jsonl", false, "emit json lines format, one issue per line")
+	flagSet.BoolVar(&a.outJUnit, "junit", false, "emit junit xml format")
+	flagSet.BoolVar(&a.outCheckstyle, "checkstyle", false, "emit checkstyle xml format")
+	flagSet.BoolVar(&a.outSARIF, "sarif", false, "emit sarif 2.1.0 format")
+	flagSet.BoolVar(&a.outGitHub, "github-actions", false, "emit github actions workflow commands to annotate issues")
+	flagSet.BoolVar(&a.outLSP, "lsp-diagnostics", false, "emit lsp publishDiagnostics parameters per file in json")
+	flagSet.BoolVar(&a.outSonar, "sonar", false, "emit sonarqube generic issue format")
+	flagSet.BoolVar(&a.flatness, "flatness", false, "show the ratio of guard clauses to nested blocks as a flatness score, inside the report with --json")
+	flagSet.BoolVar(&a.topo, "topo", false, "order issues so that packages come before the packages importing them")
+	flagSet.BoolVar(&a.offsets, "offsets", false, "show byte offsets instead of line and column")
+	flagSet.BoolVar(&a.showSource, "show-source", false, "show the source of each if statement, up to 10 lines")
+	flagSet.BoolVar(&a.relative, "relative", false, "show file paths relative to the current directory; by default they are shown as given or found")
+	flagSet.BoolVar(&a.absolute, "abs", false, "show absolute file paths")
+	flagSet.BoolVar(&a.editorConfig, "editorconfig", false, "show columns with tabs expanded to the tab_width or indent_size in the nearest .editorconfig")
+	flagSet.BoolVar(&a.benchfmt, "benchfmt", false, "print one line per package in go benchmark format, to track complexity with benchstat-like tools")
+	flagSet.BoolVar(&a.failOnIssues, "fail-on-issues", false, "exit with 1 if any issue is found")
+	flagSet.IntVar(&a.failOver, "fail-over", 0, "exit with 1 if any issue has the given complexity or more; 0 means no threshold")
+	flagSet.BoolVar(&a.listFiles, "list-files", false, "print the files that would be checked, after expanding directories and packages and excluding files, without checking them")
+	flagSet.BoolVar(&a.checkOnly, "check", false, "print nothing and exit with 1 if any issue is found, e.g. for git hooks")
+	flagSet.BoolVar(&a.summary, "summary", false, "show the numbers of files and issues, and the max and average complexities, of all issues found before --top")
+	flagSet.BoolVar(&a.histogram, "histogram", false, "print a histogram of the number of issues per complexity range")
+	flagSet.BoolVar(&a.table, "table", false, "print issues as an aligned table of file, line, complexity and condition")
+	flagSet.BoolVar(&a.brief, "brief", false, "print one line per file with its issue count and complexities")
+	flagSet.BoolVar(&a.annotate, "annotate", false, "print files with complexity comments inserted above flagged if statements")
+	flagSet.StringArrayVar(&a.mins, "min", []string{"1"}, "minimum complexity to show; give rule=N to set it per rule, e.g. nested-if=3")
+	flagSet.IntVar(&a.maxComplexity, "max", 0, "maximum complexity to show; 0 means no upper bound")
+	flagSet.IntVar(&a.minLines, "min-lines", 0, "minimum number of lines from the first line to the last one of if statements to show; 0 means no limit")
+	flagSet.IntVar(&a.warnComplexity, "warn-complexity", 0, "complexity from which issues are warnings rather than info; 0 means all are warnings")
+	flagSet.IntVar(&a.errorComplexity, "error-complexity", 0, "complexity from which issues are errors rather than warnings; 0 means none are errors")
+	flagSet.StringArrayVar(&a.failLevels, "fail-level", []string{}, "exit with 1 if issues of the rule reach the severity, set by --warn-complexity and --error-complexity, given as rule=info|warning|error; can be given multiple times")
+	flagSet.StringVar(&a.reportAt, "report-at", "root", "where to report issues: root or deepest if statement")
+	flagSet.BoolVar(&a.ignoreEmpty, "ignore-empty", false, "treat if statements with an empty body as zero complexity")
+	flagSet.BoolVar(&a.includeSwitch, "include-switch", false, "count switch statements nested in if statements toward complexity")
+	flagSet.BoolVar(&a.includeLoops, "include-loops", false, "count for and range loops as nesting toward complexity")
+	flagSet.BoolVar(&a.includeSelect, "include-select", false, "count select statements as nesting toward complexity")
+	flagSet.BoolVar(&a.keepClosures, "keep-closure-nesting", false, "let ifs in function literals continue from the enclosing nesting level instead of starting from 0")
+	flagSet.BoolVar(&a.booleanOps, "include-boolean-ops", false, "add complexity for each sequence of like && or || operators in conditions")
+	flagSet.BoolVar(&a.ifErr, "if-err", false, "count if err != nil blocks, which are ignored by default")
+	flagSet.IntVar(&a.elseIfPenalty, "else-if-chain-penalty", 0, "extra complexity for each else if beyond --max-else-if-chain in a chain")
+	flagSet.BoolVar(&a.includeLabels, "include-labels", false, "add complexity for each labeled statement and jump to a label, like goto, in if statements")
+	flagSet.IntVar(&a.initPenalty, "init-clause-penalty", 0, "extra complexity for each if with an init statement, like if err := f(); err != nil")
+	flagSet.Float64Var(&a.depthWeight, "depth-weight", 1, "exponent applied to the nesting level each nested if adds; values above 1 make deeper nesting count more")
+	elseWeight := flagSet.Int("else-weight", 1, "complexity added for each else block; 0 makes else free")
+	flagSet.IntVar(&a.maxElseIfChain, "max-else-if-chain", 0, "number of else ifs in a chain exempt from --else-if-chain-penalty")
+	flagSet.BoolVar(&a.exportedOnly, "exported-only", false, "check only exported functions and methods, by their own names regardless of the receiver type")
+	flagSet.BoolVar(&a.countNegations, "count-negations", false, "add complexity for negated conditions like !(a == b) or !!x")
+	flagSet.IntVar(&a.top, "top", 10, "show only the first N if statements after sorting")
+	flagSet.IntVar(&a.topPerFile, "top-per-file", 0, "keep only the first N if statements of each file after sorting, in every output format; 0 means no limit")
+	flagSet.StringVar(&a.sortBy, "sort", "complexity", "order of issues: complexity (then file, line and column), file (then line and column) or none (order found)")
+	flagSet.Float64Var(&a.maxAvg, "max-avg-complexity", 0, "exit with 1 if the average complexity per function exceeds the given value; 0 means no limit")
+	flagSet.BoolVar(&a.includeGenerated, "include-generated", false, "check generated files as well")
+	flagSet.BoolVar(&a.onlyGenerated, "only-generated", false, "check only generated files, e.g. to audit code generators")
+	flagSet.Int64Var(&a.maxFileSize, "max-file-size", 0, "skip files larger than the given bytes; 0 means unlimited")
 	flagSet.StringSliceVarP(&a.excludeDirs, "exclude-dirs", "e", []string{}, "regexps of directories to be excluded for checking; comma-separated list")
+	flagSet.BoolVar(&a.respectGitignore, "respect-gitignore", false, "skip directories ignored by .gitignore when walking with /...")
+	flagSet.StringSliceVar(&a.excludeFiles, "exclude-files", []string{}, "regexps of file paths to be excluded for checking; comma-separated list")
+	flagSet.StringArrayVar(&a.excludeConds, "exclude-cond", []string{}, "regexp of conditions to be excluded from reporting; can be given multiple times")
+	flagSet.StringVar(&a.changedFuncs, "changed-funcs", "", "given old=new file paths, check only the functions changed in the new one")
+	flagSet.StringVar(&a.indexPath, "index", "", "reuse the results of unchanged files kept in the given file by content hash, and update it")
+	flagSet.StringVar(&a.stdinPath, "stdin-filepath", "", "file path to show for the source read from stdin given as -, instead of <stdin>")
+	flagSet.BoolVar(&a.goList, "go-list", false, "check packages read from go list -json output on stdin")
+	flagSet.StringSliceVar(&a.buildTags, "build-tags", []string{}, "build tags to satisfy when selecting the files of packages, in addition to GOOS, GOARCH, cgo and the go1.N release tags; comma-separated list")
+	flagSet.BoolVar(&a.skipExternal, "skip-external", false, "check only packages of the main module, skipping GOROOT, the module cache and other modules")
+	flagSet.BoolVar(&a.byDir, "by-dir", false, "show the number of issues and total and max complexity per directory, ranked by total complexity")
+	flagSet.BoolVar(&a.byAuthor, "by-author", false, "show the number of issues and total complexity per git author")
+	flagSet.BoolVar(&a.priority, "priority", false, "rank issues by complexity times function lines times git commits to the file, to find what to fix first")
+	flagSet.StringVar(&a.baseline, "baseline", "", "report only if statements not in the given baseline file, which identifies them by file, function and condition rather than line")
+	flagSet.BoolVar(&a.writeBaseline, "write-baseline", false, "write the if statements found to the --baseline file, so that later runs report only new ones")
+	flagSet.BoolVar(&a.mine, "mine", false, "show only if statements last authored by the current git user")
+	flagSet.StringVar(&a.cpuProfile, "cpuprofile", "", "write a cpu profile to the given file")
+	flagSet.StringVar(&a.memProfile, "memprofile", "", "write a heap profile to the given file at exit")
+	flagSet.MarkHidden("cpuprofile")
+	flagSet.MarkHidden("memprofile")
+	configPath := flagSet.String("config", "", "path to the configuration file; .nestif.yml or .nestif.yaml in the current directory by default")
 	flagSet.Usage = usage
 	if err := flagSet.Parse(os.Args[1:]); err != nil {
 		if err != flag.ErrHelp {
 			fmt.Fprintln(a.stderr, err)
+			os.Exit(exitUsage)
 		}
 		return
 	}
+	// Leave it nil unless given, to keep the Checker's default.
+	if flagChanged("else-weight") {
+		a.elseWeight = elseWeight
+	}
+	if err := a.loadConfig(*configPath, flagChanged); err != nil {
+		fmt.Fprintln(a.stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	stop, err := a.startProfiles()
+	if err != nil {
+		fmt.Fprintln(a.stderr, err)
+		os.Exit(exitError)
+	}
+	code := a.run(flagSet.Args())
+	stop()
+	os.Exit(code)
+}
 
-	os.Exit(a.run(flagSet.Args()))
+// Exit codes of nestif.
+const (
+	// No issues found, or they don't fail the run.
+	exitOK = 0
+	// Issues found that fail the run, by --fail-on-issues, --check and the like.
+	exitIssues = 1
+	// Invalid flags or configuration.
+	exitUsage = 2
+	// Files that failed to parse, packages that failed to load, or any other error.
+	exitError = 3
+)
+
+// usageError is an error caused by invalid flag values.
+type usageError struct {
+	error
+}
+
+func usageErrorf(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// exitCodeOf returns the exit code for the error.
+func exitCodeOf(err error) int {
+	if _, ok := err.(usageError); ok {
+		return exitUsage
+	}
+	return exitError
 }
 
 func (a *app) run(args []string) int {
+	failLevels, err := parseFailLevels(a.failLevels)
+	if err != nil {
+		fmt.Fprintln(a.stderr, err)
+		return exitUsage
+	}
+	if a.sortBy != "" && a.sortBy != "complexity" && a.sortBy != "file" && a.sortBy != "none" {
+		fmt.Fprintf(a.stderr, "invalid sort value: %q\n", a.sortBy)
+		return exitUsage
+	}
+	if a.writeBaseline && a.baseline == "" {
+		fmt.Fprintln(a.stderr, "--write-baseline requires --baseline")
+		return exitUsage
+	}
+	if a.relative && a.absolute {
+		fmt.Fprintln(a.stderr, "--relative and --abs can't be given together")
+		return exitUsage
+	}
+	if err := a.setFormat(); err != nil {
+		fmt.Fprintln(a.stderr, err)
+		return exitUsage
+	}
+	if modes := a.outputModes(); len(modes) > 1 {
+		fmt.Fprintf(a.stderr, "only one output format can be given, but got %s\n", strings.Join(modes, ", "))
+		return exitUsage
+	}
+	// The score would break a structured output if printed after it. JSON has it inside.
+	if a.flatness {
+		for _, f := range a.outputFormats() {
+			if *f.on && f.structured && f.name != "json" {
+				fmt.Fprintf(a.stderr, "--flatness can't be given with --%s\n", f.name)
+				return exitUsage
+			}
+		}
+	}
 	issues, err := a.check(args)
 	if err != nil {
 		fmt.Fprintln(a.stderr, err)
-		return 1
+		return exitCodeOf(err)
+	}
+	if a.listFiles {
+		return exitOK
+	}
+	if a.relative || a.absolute {
+		a.normalizePaths(issues)
+	}
+	issues, err = a.filterConds(issues)
+	if err != nil {
+		fmt.Fprintln(a.stderr, err)
+		return exitUsage
+	}
+	if a.mine {
+		issues, err = a.filterMine(issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return exitError
+		}
+	}
+	if a.writeBaseline {
+		if err := writeBaseline(a.baseline, issues); err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return exitError
+		}
+	}
+	if a.baseline != "" {
+		issues, err = filterBaseline(a.baseline, issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return exitError
+		}
+	}
+	if a.checkOnly {
+		if a.parseErrors > 0 || a.loadErrors > 0 {
+			return exitError
+		}
+		if len(issues) > 0 {
+			return exitIssues
+		}
+		return exitOK
+	}
+	if len(issues) == 0 {
+		a.warnTooHighMin()
+	}
+	a.sortIssues(issues)
+	if a.topo {
+		a.sortTopologically(issues)
+	}
+	if a.editorConfig {
+		a.expandColumns(issues)
 	}
-	sort.Slice(issues, func(i, j int) bool {
-		return issues[i].Complexity > issues[j].Complexity
-	})
 
+	// Only the report goes to the output file, while warnings and errors stay on stderr.
+	stdout := a.stdout
+	var out *os.File
+	if a.output != "" {
+		out, err = os.Create(a.output)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "failed to create the output file: %v\n", err)
+			return exitError
+		}
+		a.stdout = out
+	}
 	a.write(issues)
-	return 0
+	if a.flatness && !a.outJSON {
+		a.writeFlatness(len(issues))
+	}
+	a.stdout = stdout
+	if out != nil {
+		if err := out.Close(); err != nil {
+			fmt.Fprintf(a.stderr, "failed to write the output file: %v\n", err)
+			return exitError
+		}
+	}
+	// Results missing some files can't be trusted to pass or fail.
+	if a.parseErrors > 0 {
+		fmt.Fprintf(a.stderr, "failed to parse %s\n", plural(a.parseErrors, "file"))
+		return exitError
+	}
+	if a.loadErrors > 0 {
+		return exitError
+	}
+	if a.failOnIssues && len(issues) > 0 {
+		return exitIssues
+	}
+	if a.failOver > 0 {
+		for _, issue := range issues {
+			if issue.Complexity >= a.failOver {
+				fmt.Fprintf(a.stderr, "issues with complexity %d or more found\n", a.failOver)
+				return exitIssues
+			}
+		}
+	}
+	if level, ok := failLevels[ruleNestedIf]; ok {
+		for _, issue := range issues {
+			if severityNames[issue.Severity] >= level {
+				fmt.Fprintf(a.stderr, "%s issues reached the fail level\n", ruleNestedIf)
+				return exitIssues
+			}
+		}
+	}
+	if a.maxAvg > 0 {
+		if avg := a.avgComplexity(); avg > a.maxAvg {
+			fmt.Fprintf(a.stderr, "average complexity %.2f exceeds %.2f\n", avg, a.maxAvg)
+			return exitIssues
+		}
+	}
+	return exitOK
+}
+
+// outputFormat is an output format other than the default text, by the name
+// of its flag, which --format takes as well.
+type outputFormat struct {
+	name string
+	on   *bool
+	// Read by tools, so nothing else can be printed along with it.
+	structured bool
+}
+
+func (a *app) outputFormats() []outputFormat {
+	return []outputFormat{
+		{"json", &a.outJSON, true},
+		{"jsonl", &a.outJSONL, true},
+		{"junit", &a.outJUnit, true},
+		{"checkstyle", &a.outCheckstyle, true},
+		{"sarif", &a.outSARIF, true},
+		{"github-actions", &a.outGitHub, true},
+		{"lsp-diagnostics", &a.outLSP, true},
+		{"sonar", &a.outSonar, true},
+		{"benchfmt", &a.benchfmt, true},
+		{"histogram", &a.histogram, false},
+		{"table", &a.table, false},
+		{"brief", &a.brief, false},
+		{"annotate", &a.annotate, false},
+		{"by-dir", &a.byDir, false},
+		{"by-author", &a.byAuthor, false},
+		{"priority", &a.priority, false},
+	}
+}
+
+// setFormat turns on the output format chosen by --format.
+func (a *app) setFormat() error {
+	if a.format == "" || a.format == "text" {
+		return nil
+	}
+	for _, f := range a.outputFormats() {
+		if f.name == a.format {
+			*f.on = true
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format value: %q", a.format)
+}
+
+// outputModes gives the names of the output formats given, of which there
+// can be only one.
+func (a *app) outputModes() []string {
+	var given []string
+	for _, f := range a.outputFormats() {
+		if *f.on {
+			given = append(given, f.name)
+		}
+	}
+	return given
+}
+
+// ruleNestedIf is the rule that reports complex nested if statements. It's the
+// rule ID in every output having one, so that the same issue is matched alike.
+const ruleNestedIf = "nested-if"
+
+// knownRules are the rules whose minimum complexity can be set by --min rule=N.
+var knownRules = map[string]bool{
+	ruleNestedIf: true,
+}
+
+// resolveMin gives the minimum complexity for the rule from the --min values,
+// each of which is either N for all rules or rule=N for a specific rule.
+// The rule-specific value takes precedence; the last one wins if given repeatedly.
+func resolveMin(values []string, rule string) (int, error) {
+	var global, specific int
+	var hasSpecific bool
+	for _, v := range values {
+		name, num := "", v
+		if i := strings.Index(v, "="); i >= 0 {
+			name, num = v[:i], v[i+1:]
+			if !knownRules[name] {
+				return 0, fmt.Errorf("unknown rule for --min: %q", name)
+			}
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --min value %q: %v", v, err)
+		}
+		switch name {
+		case "":
+			global = n
+		case rule:
+			specific, hasSpecific = n, true
+		}
+	}
+	if hasSpecific {
+		return specific, nil
+	}
+	return global, nil
+}
+
+// filterConds drops issues whose condition matches any of the exclude-cond patterns.
+func (a *app) filterConds(issues []nestif.Issue) ([]nestif.Issue, error) {
+	if len(a.excludeConds) == 0 {
+		return issues, nil
+	}
+	patterns := make([]*regexp.Regexp, 0, len(a.excludeConds))
+	for _, c := range a.excludeConds {
+		p, err := regexp.Compile(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse exclude cond pattern: %v", err)
+		}
+		patterns = append(patterns, p)
+	}
+	filtered := make([]nestif.Issue, 0, len(issues))
+	for _, issue := range issues {
+		excluded := false
+		for _, p := range patterns {
+			if p.MatchString(issue.Condition) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered, nil
+}
+
+// warnTooHighMin hints that nothing was reported because of the threshold,
+// while some nested ifs were actually found.
+func (a *app) warnTooHighMin() {
+	var max int
+	for _, f := range a.funcs {
+		if f.MaxComplexity > max {
+			max = f.MaxComplexity
+		}
+	}
+	if max > 0 && max < a.minComplexity {
+		fmt.Fprintf(a.notices(), "warning: no issues with complexity %d or more; the highest complexity found is %d\n", a.minComplexity, max)
+	}
+}
+
+// flatness is the ratio of guard clauses to all of guard clauses and nested blocks.
+// The more guard clauses instead of nesting, the closer to 1 it gets.
+type flatness struct {
+	Score  float64 `json:"score"`
+	Guards int     `json:"guardClauses"`
+	Nested int     `json:"nestedBlocks"`
+}
+
+func (a *app) flatnessOf(nested int) flatness {
+	fl := flatness{Score: 1.0, Nested: nested}
+	for _, f := range a.funcs {
+		fl.Guards += f.Guards
+	}
+	if fl.Guards+nested > 0 {
+		fl.Score = float64(fl.Guards) / float64(fl.Guards+nested)
+	}
+	return fl
+}
+
+func (a *app) writeFlatness(nested int) {
+	fl := a.flatnessOf(nested)
+	fmt.Fprintf(a.stdout, "flatness score: %.2f (guard clauses: %d, nested blocks: %d)\n", fl.Score, fl.Guards, fl.Nested)
+}
+
+// avgComplexity gives the mean complexity of all functions having if statements.
+func (a *app) avgComplexity() float64 {
+	if len(a.funcs) == 0 {
+		return 0
+	}
+	var sum int
+	for _, f := range a.funcs {
+		sum += f.Complexity
+	}
+	return float64(sum) / float64(len(a.funcs))
 }
 
 func (a *app) check(args []string) ([]nestif.Issue, error) {
@@ -90,27 +610,93 @@ func (a *app) check(args []string) ([]nestif.Issue, error) {
 	for _, d := range a.excludeDirs {
 		p, err := regexp.Compile(d)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse exclude dir pattern: %v", err)
+			return nil, usageErrorf("failed to parse exclude dir pattern: %v", err)
 		}
 		a.excludePatterns = append(a.excludePatterns, p)
 	}
+	a.excludeFilePats = make([]*regexp.Regexp, 0, len(a.excludeFiles))
+	for _, f := range a.excludeFiles {
+		p, err := regexp.Compile(f)
+		if err != nil {
+			return nil, usageErrorf("failed to parse exclude file pattern: %v", err)
+		}
+		a.excludeFilePats = append(a.excludeFilePats, p)
+	}
+
+	if len(a.mins) > 0 {
+		min, err := resolveMin(a.mins, ruleNestedIf)
+		if err != nil {
+			return nil, usageError{err}
+		}
+		a.minComplexity = min
+	}
+	if a.reportAt != "" && a.reportAt != "root" && a.reportAt != "deepest" {
+		return nil, usageErrorf("invalid report-at value: %q", a.reportAt)
+	}
 
 	checker := &nestif.Checker{
-		MinComplexity: a.minComplexity,
+		MinComplexity:      a.minComplexity,
+		MaxComplexity:      a.maxComplexity,
+		MinLines:           a.minLines,
+		ReportAtDeepest:    a.reportAt == "deepest",
+		CountNegations:     a.countNegations,
+		IgnoreEmptyBody:    a.ignoreEmpty,
+		IncludeSwitch:      a.includeSwitch,
+		IncludeLoops:       a.includeLoops,
+		IncludeSelect:      a.includeSelect,
+		KeepClosureNesting: a.keepClosures,
+		IncludeBooleanOps:  a.booleanOps,
+		IfErr:              a.ifErr,
+		ElseIfChainPenalty: a.elseIfPenalty,
+		MaxElseIfChain:     a.maxElseIfChain,
+		ElseWeight:         a.elseWeight,
+		DepthWeight:        a.depthWeight,
+		InitClausePenalty:  a.initPenalty,
+		IncludeLabels:      a.includeLabels,
+		ExportedOnly:       a.exportedOnly,
+		WarnComplexity:     a.warnComplexity,
+		ErrorComplexity:    a.errorComplexity,
 	}
-	if a.verbose {
+	if a.verbose && !a.quiet {
 		checker.DebugMode(a.stderr)
 	}
+	// Listing files doesn't use the index, which would otherwise be saved empty.
+	if a.indexPath != "" && !a.listFiles {
+		options, err := a.indexOptions(checker)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := loadIndex(a.indexPath, options)
+		if err != nil {
+			return nil, err
+		}
+		a.index = idx
+		defer func() {
+			if err := idx.save(a.indexPath); err != nil {
+				fmt.Fprintln(a.stderr, err)
+			}
+		}()
+	}
+	if a.goList {
+		return a.checkGoList(checker, a.stdin)
+	}
+	if a.changedFuncs != "" {
+		return a.checkChangedFuncs(checker, a.changedFuncs)
+	}
 
 	// TODO: Reduce allocation.
 	var files, dirs, pkgs []string
+	var found []pkgDir
+	var stdin bool
 	// Check all files recursively when no args given.
 	if len(args) == 0 {
-		dirs = append(dirs, allPackagesInFS("./...", a.stderr)...)
+		found = append(found, a.allPackagesInFS("./...")...)
 	}
 	for _, arg := range args {
-		if strings.HasSuffix(arg, "/...") && isDir(arg[:len(arg)-len("/...")]) {
-			dirs = append(dirs, allPackagesInFS(arg, a.stderr)...)
+		if arg == "-" {
+			stdin = true
+		} else if strings.HasSuffix(arg, "/...") && isDir(arg[:len(arg)-len("/...")]) {
+			found = append(found, a.allPackagesInFS(arg)...)
 		} else if isDir(arg) {
 			dirs = append(dirs, arg)
 		} else if exists(arg) {
@@ -120,114 +706,294 @@ func (a *app) check(args []string) ([]nestif.Issue, error) {
 		}
 	}
 
-	var issues []nestif.Issue
-	for _, f := range files {
-		is, err := a.checkFile(checker, f)
+	// Packages are all resolved first, so that the files reached more than once
+	// are known before checking, and only those are kept parsed.
+	for _, d := range dirs {
+		ds, err := a.dirPackages(d)
 		if err != nil {
 			a.debug(err)
 			continue
 		}
-		issues = append(issues, is...)
+		found = append(found, ds...)
 	}
-	for _, d := range dirs {
-		is, err := a.checkDir(checker, d)
+	for _, p := range pkgs {
+		ds, err := a.importPathPackages(p)
 		if err != nil {
-			a.debug(err)
+			fmt.Fprintln(a.stderr, err)
+			a.loadErrors++
 			continue
 		}
+		found = append(found, ds...)
+	}
+	a.countRefs(files, found)
+
+	var issues []nestif.Issue
+	if stdin {
+		is, err := a.checkStdin(checker)
+		if err != nil {
+			a.debug(err)
+		}
 		issues = append(issues, is...)
 	}
-	for _, p := range pkgs {
-		is, err := a.checkPackage(checker, p)
+	for _, f := range files {
+		is, err := a.checkFile(checker, f)
 		if err != nil {
-			fmt.Fprintln(a.stdout, err)
+			a.debug(err)
 			continue
 		}
 		issues = append(issues, is...)
 	}
+	for _, d := range found {
+		issues = append(issues, a.checkPkgDir(checker, d)...)
+	}
 	return issues, nil
 }
 
 func (a *app) checkFile(checker *nestif.Checker, path string) ([]nestif.Issue, error) {
+	defer a.release(path)
 	dir := filepath.Dir(path)
 	for _, p := range a.excludePatterns {
 		if p.MatchString(dir) {
 			return []nestif.Issue{}, nil
 		}
 	}
+	for _, p := range a.excludeFilePats {
+		if p.MatchString(path) {
+			return []nestif.Issue{}, nil
+		}
+	}
+	if a.listFiles {
+		fmt.Fprintln(a.stdout, path)
+		return []nestif.Issue{}, nil
+	}
+
+	if a.maxFileSize > 0 {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Size() > a.maxFileSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", path, a.maxFileSize)
+		}
+	}
 
-	src, err := ioutil.ReadFile(path)
+	src, err := a.readFile(path)
 	if err != nil {
 		return nil, err
 	}
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	return a.checkSource(checker, path, src)
+}
+
+// checkStdin checks the Go source read from stdin, as a file named by
+// --stdin-filepath, or "<stdin>" if not given.
+func (a *app) checkStdin(checker *nestif.Checker) ([]nestif.Issue, error) {
+	name := "<stdin>"
+	if a.stdinPath != "" {
+		name = a.stdinPath
+	}
+	if a.listFiles {
+		fmt.Fprintln(a.stdout, name)
+		return []nestif.Issue{}, nil
+	}
+	src, err := ioutil.ReadAll(a.stdin)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read stdin: %v", err)
 	}
-	if len(f.Comments) > 0 && isGenerated(src) {
+	return a.checkSource(checker, name, src)
+}
+
+func (a *app) checkSource(checker *nestif.Checker, path string, src []byte) ([]nestif.Issue, error) {
+	if a.showSource {
+		if a.sources == nil {
+			a.sources = make(map[string][]byte)
+		}
+		a.sources[path] = src
+	}
+	if a.index != nil {
+		if e, ok := a.index.lookup(path, src); ok {
+			a.funcs = append(a.funcs, e.Funcs...)
+			return e.Issues, nil
+		}
+	}
+	fset, f, err := a.parse(path, src)
+	if err != nil {
+		// It makes the run fail, so it's always told, unlike files skipped.
+		fmt.Fprintln(a.stderr, err)
+		a.parseErrors++
+		return nil, nil
+	}
+	generated := len(f.Comments) > 0 && nestif.IsGenerated(src)
+	if a.onlyGenerated && !generated {
+		return nil, fmt.Errorf("%s is not a generated file", path)
+	}
+	if !a.includeGenerated && !a.onlyGenerated && generated {
 		return nil, fmt.Errorf("%s is a generated file", path)
 	}
 
-	return checker.Check(f, fset), nil
+	issues, funcs := checker.CheckFuncs(f, fset)
+	if parsedAs := fset.File(f.Pos()).Name(); parsedAs != path {
+		renamePositions(issues, funcs, parsedAs, path)
+	}
+	a.funcs = append(a.funcs, funcs...)
+	if a.index != nil {
+		a.index.store(src, indexEntry{Issues: issues, Funcs: funcs})
+	}
+	return issues, nil
 }
 
-// Copyright (c) 2013 The Go Authors. All rights reserved.
-//
-// Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file or at
-// https://developers.google.com/open-source/licenses/bsd.
-func (a *app) checkDir(checker *nestif.Checker, dirname string) ([]nestif.Issue, error) {
+// dirPackages gives the package in the directory, shown as dirname as is.
+func (a *app) dirPackages(dirname string) ([]pkgDir, error) {
 	for _, p := range a.excludePatterns {
 		if p.MatchString(dirname) {
-			return []nestif.Issue{}, nil
+			return nil, nil
 		}
 	}
-	pkg, err := build.ImportDir(dirname, 0)
+	pkgs, err := a.loadPackages(dirname, ".")
 	if err != nil {
-		if _, nogo := err.(*build.NoGoError); nogo {
-			// Don't complain if the failure is due to no Go source files.
-			return nil, nil
-		}
 		return nil, err
 	}
-	return a.checkImportedPackage(checker, pkg)
+	return pkgDirs(pkgs, func(string) string { return dirname }), nil
 }
 
-func (a *app) checkPackage(checker *nestif.Checker, pkgname string) ([]nestif.Issue, error) {
-	pkg, err := build.Import(pkgname, ".", 0)
-	if err != nil {
-		if _, nogo := err.(*build.NoGoError); nogo {
-			// Don't complain if the failure is due to no Go source files.
-			return nil, nil
+// checkPkgDir checks the files of the package directory, unless the directory
+// is excluded or external and --skip-external is given.
+func (a *app) checkPkgDir(checker *nestif.Checker, d pkgDir) (issues []nestif.Issue) {
+	for _, p := range a.excludePatterns {
+		if p.MatchString(d.Dir) {
+			return nil
 		}
-		return nil, err
 	}
-	return a.checkImportedPackage(checker, pkg)
-}
-
-func (a *app) checkImportedPackage(checker *nestif.Checker, pkg *build.Package) (issues []nestif.Issue, err error) {
-	var files []string
-	files = append(files, pkg.GoFiles...)
-	files = append(files, pkg.CgoFiles...)
-	files = append(files, pkg.TestGoFiles...)
+	if a.skipExternal && isExternal(d.Dir) {
+		return nil
+	}
 	// TODO: Reduce allocation.
-	if pkg.Dir != "." {
-		for _, f := range files {
-			is, err := a.checkFile(checker, filepath.Join(pkg.Dir, f))
-			if err != nil {
-				a.debug(err)
-				continue
-			}
-			issues = append(issues, is...)
+	for _, f := range d.Files {
+		is, err := a.checkFile(checker, filepath.Join(d.Dir, f))
+		if err != nil {
+			a.debug(err)
+			continue
 		}
+		issues = append(issues, is...)
 	}
 	return
 }
 
 func (a *app) write(issues []nestif.Issue) {
+	// Counted before the limits, like the score printed after the text output.
+	nested := len(issues)
+	if a.topPerFile > 0 {
+		issues = limitPerFile(issues, a.topPerFile)
+	}
 	if a.outJSON {
-		js, err := json.Marshal(issues)
+		var v interface{} = issues
+		if a.summary || a.flatness {
+			report := struct {
+				Issues   []nestif.Issue `json:"issues"`
+				Summary  *summary       `json:"summary,omitempty"`
+				Flatness *flatness      `json:"flatness,omitempty"`
+			}{Issues: issues}
+			if a.summary {
+				s := summarize(issues)
+				report.Summary = &s
+			}
+			if a.flatness {
+				fl := a.flatnessOf(nested)
+				report.Flatness = &fl
+			}
+			v = report
+		}
+		js, err := json.Marshal(v)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return
+		}
+		fmt.Fprintln(a.stdout, string(js))
+		return
+	}
+	if a.outJSONL {
+		enc := json.NewEncoder(a.stdout)
+		for _, issue := range issues {
+			if err := enc.Encode(issue); err != nil {
+				fmt.Fprintln(a.stderr, err)
+				return
+			}
+		}
+		return
+	}
+	if a.outJUnit {
+		x, err := junitReport(issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return
+		}
+		fmt.Fprintln(a.stdout, string(x))
+		return
+	}
+	if a.outSARIF {
+		js, err := sarifJSON(issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return
+		}
+		fmt.Fprintln(a.stdout, string(js))
+		return
+	}
+	if a.outCheckstyle {
+		x, err := checkstyleReport(issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return
+		}
+		fmt.Fprintln(a.stdout, string(x))
+		return
+	}
+	if a.outLSP {
+		js, err := lspJSON(issues)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return
+		}
+		fmt.Fprintln(a.stdout, string(js))
+		return
+	}
+	if a.outGitHub {
+		a.writeGitHubActions(issues)
+		return
+	}
+	if a.annotate {
+		a.writeAnnotated(issues)
+		return
+	}
+	if a.byDir {
+		a.writeByDir(issues)
+		return
+	}
+	if a.byAuthor {
+		a.writeByAuthor(issues)
+		return
+	}
+	if a.priority {
+		a.writePriority(issues)
+		return
+	}
+	if a.brief {
+		a.writeBrief(issues)
+		return
+	}
+	if a.benchfmt {
+		a.writeBenchfmt(issues)
+		return
+	}
+	if a.histogram {
+		a.writeHistogram(issues)
+		return
+	}
+	if a.table {
+		a.writeTable(issues)
+		return
+	}
+	if a.outSonar {
+		js, err := sonarJSON(issues)
 		if err != nil {
 			fmt.Fprintln(a.stderr, err)
 			return
@@ -237,18 +1003,332 @@ func (a *app) write(issues []nestif.Issue) {
 	}
 	for i, issue := range issues {
 		if i >= a.top {
-			return
+			break
+		}
+		msg := issue.Message
+		if a.verbose && issue.Path != "" {
+			msg = fmt.Sprintf("%s [%s]", msg, issue.Path)
+		}
+		if a.offsets {
+			fmt.Fprintf(a.stdout, "%s:%d: %s\n", issue.Pos.Filename, issue.Pos.Offset, msg)
+			continue
+		}
+		fmt.Fprintln(a.stdout, errformat(issue.Pos.Filename, issue.Pos.Line, issue.Pos.Column, msg))
+		if a.showSource {
+			a.writeSource(issue)
+		}
+	}
+	if a.summary {
+		s := summarize(issues)
+		fmt.Fprintf(a.stdout, "%s, %s, max complexity %d, average %.1f\n",
+			plural(s.Files, "file"), plural(s.Issues, "issue"), s.MaxComplexity, s.AvgComplexity)
+	}
+}
+
+// maxSourceLines is the maximum number of lines shown by --show-source.
+const maxSourceLines = 10
+
+// writeSource prints the lines of the if statement of the issue with line numbers,
+// from its first line up to maxSourceLines.
+func (a *app) writeSource(issue nestif.Issue) {
+	src, ok := a.sources[issue.Pos.Filename]
+	if !ok {
+		return
+	}
+	lines := strings.Split(string(src), "\n")
+	last := issue.EndPos.Line
+	if last < issue.Pos.Line {
+		last = issue.Pos.Line
+	}
+	truncated := last-issue.Pos.Line+1 > maxSourceLines
+	if truncated {
+		last = issue.Pos.Line + maxSourceLines - 1
+	}
+	for l := issue.Pos.Line; l <= last && l <= len(lines); l++ {
+		fmt.Fprintf(a.stdout, "    %4d | %s\n", l, lines[l-1])
+	}
+	if truncated {
+		fmt.Fprintln(a.stdout, "         | ...")
+	}
+}
+
+// summary is the aggregate of issues.
+type summary struct {
+	Files         int     `json:"files"`
+	Issues        int     `json:"issues"`
+	MaxComplexity int     `json:"maxComplexity"`
+	AvgComplexity float64 `json:"avgComplexity"`
+}
+
+func summarize(issues []nestif.Issue) summary {
+	s := summary{Issues: len(issues)}
+	files := make(map[string]bool)
+	var total int
+	for _, issue := range issues {
+		files[issue.Pos.Filename] = true
+		total += issue.Complexity
+		if issue.Complexity > s.MaxComplexity {
+			s.MaxComplexity = issue.Complexity
+		}
+	}
+	s.Files = len(files)
+	if len(issues) > 0 {
+		s.AvgComplexity = float64(total) / float64(len(issues))
+	}
+	return s
+}
+
+// plural gives the count with the noun, adding "s" unless the count is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// normalizePaths turns the file paths of the issues into absolute ones, or ones
+// relative to the current directory, as given by --abs or --relative.
+// Paths that can't be converted, like "<stdin>", are left as they are.
+func (a *app) normalizePaths(issues []nestif.Issue) {
+	wd, err := os.Getwd()
+	if err != nil {
+		a.debug(err)
+		return
+	}
+	paths := make(map[string]string)
+	normalize := func(name string) string {
+		if p, ok := paths[name]; ok {
+			return p
+		}
+		p := name
+		if abs, err := filepath.Abs(name); err == nil && exists(name) {
+			p = abs
+			if rel, err := filepath.Rel(wd, abs); err == nil && a.relative {
+				p = rel
+			}
 		}
-		fmt.Fprintln(a.stdout, errformat(issue.Pos.Filename, issue.Pos.Line, issue.Pos.Column, issue.Message))
+		paths[name] = p
+		if src, ok := a.sources[name]; ok {
+			a.sources[p] = src
+		}
+		return p
+	}
+	for i := range issues {
+		issues[i].Pos.Filename = normalize(issues[i].Pos.Filename)
+		issues[i].EndPos.Filename = normalize(issues[i].EndPos.Filename)
+	}
+}
+
+// limitPerFile keeps up to n issues of each file, in the given order.
+func limitPerFile(issues []nestif.Issue, n int) []nestif.Issue {
+	counts := make(map[string]int)
+	kept := make([]nestif.Issue, 0, len(issues))
+	for _, issue := range issues {
+		if counts[issue.Pos.Filename] >= n {
+			continue
+		}
+		counts[issue.Pos.Filename]++
+		kept = append(kept, issue)
+	}
+	return kept
+}
+
+// sortIssues orders issues as given by --sort. Both sorting by complexity and
+// by file break ties on file, line and then column, so that the output is the
+// same across runs.
+func (a *app) sortIssues(issues []nestif.Issue) {
+	switch a.sortBy {
+	case "none":
+	case "file":
+		sort.SliceStable(issues, func(i, j int) bool {
+			return posLess(issues[i].Pos, issues[j].Pos)
+		})
+	default:
+		sort.Slice(issues, func(i, j int) bool {
+			if issues[i].Complexity != issues[j].Complexity {
+				return issues[i].Complexity > issues[j].Complexity
+			}
+			return posLess(issues[i].Pos, issues[j].Pos)
+		})
 	}
 }
 
+// posLess orders positions by file, line and then column.
+func posLess(p, q token.Position) bool {
+	if p.Filename != q.Filename {
+		return p.Filename < q.Filename
+	}
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.Column < q.Column
+}
+
+// writeBrief prints each file once with its issue count and complexities in descending order.
+func (a *app) writeBrief(issues []nestif.Issue) {
+	complexities := make(map[string][]int)
+	var files []string
+	for _, issue := range issues {
+		name := issue.Pos.Filename
+		if _, ok := complexities[name]; !ok {
+			files = append(files, name)
+		}
+		complexities[name] = append(complexities[name], issue.Complexity)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		// Issues may come in any order with --sort, so they're sorted here.
+		sort.Sort(sort.Reverse(sort.IntSlice(complexities[f])))
+		cs := make([]string, 0, len(complexities[f]))
+		for _, c := range complexities[f] {
+			cs = append(cs, strconv.Itoa(c))
+		}
+		noun := "issues"
+		if len(cs) == 1 {
+			noun = "issue"
+		}
+		fmt.Fprintf(a.stdout, "%s: %d %s [%s]\n", f, len(cs), noun, strings.Join(cs, ","))
+	}
+}
+
+// writeBenchfmt prints each package directory once as a benchmark result line,
+// like "BenchmarkNestif/pkg 1 9 complexity 2 issues", with the total complexity
+// and the number of issues found in it.
+func (a *app) writeBenchfmt(issues []nestif.Issue) {
+	complexities := make(map[string]int)
+	counts := make(map[string]int)
+	var pkgs []string
+	for _, issue := range issues {
+		pkg := filepath.ToSlash(filepath.Dir(issue.Pos.Filename))
+		if _, ok := counts[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		complexities[pkg] += issue.Complexity
+		counts[pkg]++
+	}
+	sort.Strings(pkgs)
+	for _, p := range pkgs {
+		fmt.Fprintf(a.stdout, "BenchmarkNestif/%s 1 %d complexity %d issues\n", p, complexities[p], counts[p])
+	}
+}
+
+type dirStat struct {
+	dir        string
+	issues     int
+	complexity int
+	max        int
+}
+
+// writeByDir prints each directory once with the number of its issues and their
+// total and max complexity, from the one with the highest total complexity.
+func (a *app) writeByDir(issues []nestif.Issue) {
+	stats := make(map[string]*dirStat)
+	for _, issue := range issues {
+		dir := filepath.Dir(issue.Pos.Filename)
+		s, ok := stats[dir]
+		if !ok {
+			s = &dirStat{dir: dir}
+			stats[dir] = s
+		}
+		s.issues++
+		s.complexity += issue.Complexity
+		if issue.Complexity > s.max {
+			s.max = issue.Complexity
+		}
+	}
+
+	list := make([]*dirStat, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].complexity != list[j].complexity {
+			return list[i].complexity > list[j].complexity
+		}
+		if list[i].max != list[j].max {
+			return list[i].max > list[j].max
+		}
+		return list[i].dir < list[j].dir
+	})
+	for _, s := range list {
+		fmt.Fprintf(a.stdout, "%s: issues: %d, total complexity: %d, max complexity: %d\n", s.dir, s.issues, s.complexity, s.max)
+	}
+}
+
+// histogramBuckets are the complexity ranges of the histogram, by their upper bounds.
+var histogramBuckets = []struct {
+	label string
+	max   int
+}{
+	{"1-2", 2},
+	{"3-5", 5},
+	{"6+", math.MaxInt32},
+}
+
+// histogramWidth is the length of the bar of the largest bucket.
+const histogramWidth = 40
+
+// writeHistogram prints the number of issues in each complexity range, with a bar
+// scaled to the largest one.
+func (a *app) writeHistogram(issues []nestif.Issue) {
+	counts := make([]int, len(histogramBuckets))
+	var most int
+	for _, issue := range issues {
+		for i, b := range histogramBuckets {
+			if issue.Complexity <= b.max {
+				counts[i]++
+				if counts[i] > most {
+					most = counts[i]
+				}
+				break
+			}
+		}
+	}
+	for i, b := range histogramBuckets {
+		bar := counts[i]
+		if most > histogramWidth {
+			bar = counts[i] * histogramWidth / most
+		}
+		fmt.Fprintf(a.stdout, "%-4s %3d %s\n", b.label+":", counts[i], strings.Repeat("#", bar))
+	}
+}
+
+// maxTableCondition is the maximum number of characters of the conditions
+// shown by --table, beyond which they are cut with an ellipsis.
+const maxTableCondition = 40
+
+// writeTable prints up to top issues as aligned columns with a header row.
+func (a *app) writeTable(issues []nestif.Issue) {
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tLINE\tCOMPLEXITY\tCONDITION")
+	for i, issue := range issues {
+		if i >= a.top {
+			break
+		}
+		cond := []rune(issue.Condition)
+		if len(cond) > maxTableCondition {
+			cond = append(cond[:maxTableCondition-3], []rune("...")...)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", issue.Pos.Filename, issue.Pos.Line, issue.Complexity, string(cond))
+	}
+	w.Flush()
+}
+
 func (a *app) debug(err error) {
-	if a.verbose {
+	if a.verbose && !a.quiet {
 		fmt.Fprintln(a.stdout, err)
 	}
 }
 
+// notices gives the writer for warnings that don't change the exit code,
+// which discards them with --quiet.
+func (a *app) notices() io.Writer {
+	if a.quiet {
+		return ioutil.Discard
+	}
+	return a.stderr
+}
+
 func isDir(filename string) bool {
 	fi, err := os.Stat(filename)
 	return err == nil && fi.IsDir()
@@ -258,20 +1338,3 @@ func exists(filename string) bool {
 	_, err := os.Stat(filename)
 	return err == nil
 }
-
-// isGenerated reports whether the source file is generated code
-// according the rules from https://golang.org/s/generatedcode.
-func isGenerated(src []byte) bool {
-	var (
-		genHdr = []byte("// Code generated ")
-		genFtr = []byte(" DO NOT EDIT.")
-	)
-	sc := bufio.NewScanner(bytes.NewReader(src))
-	for sc.Scan() {
-		b := sc.Bytes()
-		if bytes.HasPrefix(b, genHdr) && bytes.HasSuffix(b, genFtr) && len(b) >= len(genHdr)+len(genFtr) {
-			return true
-		}
-	}
-	return false
-}
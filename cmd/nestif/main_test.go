@@ -8,21 +8,76 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/nakabonne/nestif"
 )
 
 func TestRun(t *testing.T) {
 	cases := []struct {
 		name          string
+		dir           string
 		args          []string
 		verbose       bool
+		quiet         bool
 		outJSON       bool
+		outJSONL      bool
+		outJUnit      bool
+		outSonar      bool
+		outCheckstyle bool
+		outSARIF      bool
+		outGitHub     bool
+		outLSP        bool
+		annotate      bool
+		brief         bool
+		topo          bool
+		flatness      bool
+		offsets       bool
+		editorConfig  bool
+		showSource    bool
+		relative      bool
+		absolute      bool
+		benchfmt      bool
+		histogram     bool
+		byDir         bool
+		table         bool
+		format        string
+		summary       bool
+		checkOnly     bool
+		listFiles     bool
+		failOnIssues  bool
+		failOver      int
+		warnC         int
+		errorC        int
 		minComplexity int
+		mins          []string
+		failLevels    []string
+		maxComplexity int
+		minLines      int
+		reportAt      string
+		sortBy        string
 		top           int
+		topPerFile    int
+		maxFileSize   int64
+		includeGen    bool
+		onlyGen       bool
+		maxAvg        float64
 		excludeDirs   []string
+		excludeFiles  []string
+		excludeConds  []string
+		skipExternal  bool
+		buildTags     []string
+		stdin         string
+		stdinPath     string
 		want          string
 		code          int
 	}{
@@ -34,6 +89,15 @@ func TestRun(t *testing.T) {
 			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n",
 			code:          0,
 		},
+		{
+			name:          "breadcrumb in verbose output",
+			verbose:       true,
+			args:          []string{"../../testdata/f.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/f.go:7:2: `if b1` has complex nested blocks (complexity: 1) [if > for > if]\n",
+			code:          0,
+		},
 		{
 			name:          "show only top 2",
 			args:          []string{"../../testdata/d.go"},
@@ -42,6 +106,137 @@ func TestRun(t *testing.T) {
 			want:          "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n../../testdata/d.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
 			code:          0,
 		},
+		{
+			name:          "show only top 1 per file",
+			args:          []string{"../../testdata/d.go", "../../testdata/c.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			topPerFile:    1,
+			sortBy:        "file",
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/c.go:6:2: `if b1` has complex nested blocks (complexity: 4)\n../../testdata/d.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "show only top 1 per file in json",
+			outJSON:       true,
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			topPerFile:    1,
+			want:          "[{\"Pos\":{\"Filename\":\"../../testdata/d.go\",\"Offset\":152,\"Line\":16,\"Column\":2},\"EndPos\":{\"Filename\":\"../../testdata/d.go\",\"Offset\":221,\"Line\":21,\"Column\":3},\"Complexity\":3,\"Message\":\"`if b1` has complex nested blocks (complexity: 3)\",\"Condition\":\"b1\",\"Path\":\"if \\u003e if \\u003e if\",\"RelLine\":13,\"FuncName\":\"_\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":3,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}},{\"Pos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":78,\"Line\":9,\"Column\":2},\"EndPos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":125,\"Line\":12,\"Column\":3},\"Complexity\":1,\"Message\":\"`if b1` has complex nested blocks (complexity: 1)\",\"Condition\":\"b1\",\"Path\":\"if \\u003e if\",\"RelLine\":6,\"FuncName\":\"_\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":1,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}}]\n",
+			code:          0,
+		},
+		{
+			name:          "check a dirty file",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           10,
+			checkOnly:     true,
+			want:          "",
+			code:          1,
+		},
+		{
+			name:          "check a clean file",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 4,
+			top:           10,
+			checkOnly:     true,
+			want:          "",
+			code:          0,
+		},
+		{
+			name:          "fail on issues",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			failOnIssues:  true,
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          1,
+		},
+		{
+			name:          "fail on issues without issues",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 2,
+			top:           10,
+			failOnIssues:  true,
+			want:          "warning: no issues with complexity 2 or more; the highest complexity found is 1\n",
+			code:          0,
+		},
+		{
+			name:          "short if statements filtered",
+			args:          []string{"../../testdata/lines.go"},
+			minComplexity: 1,
+			top:           10,
+			minLines:      5,
+			want:          "../../testdata/lines.go:8:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "list files",
+			args:          []string{"../../testdata/a.go", "../../testdata/a/...", "../../testdata/tags"},
+			minComplexity: 1,
+			top:           10,
+			excludeFiles:  []string{"b/"},
+			listFiles:     true,
+			want:          "../../testdata/a.go\n../../testdata/a/a.go\n../../testdata/tags/a.go\n",
+			code:          0,
+		},
+		{
+			name:          "quiet without issues",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 2,
+			top:           10,
+			quiet:         true,
+			want:          "",
+			code:          0,
+		},
+		{
+			name:          "quiet with json output",
+			outJSON:       true,
+			args:          []string{"../../testdata/nogo/..."},
+			minComplexity: 1,
+			top:           10,
+			quiet:         true,
+			want:          "null\n",
+			code:          0,
+		},
+		{
+			name:          "fail over the threshold",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           1,
+			failOver:      3,
+			want:          "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\nissues with complexity 3 or more found\n",
+			code:          1,
+		},
+		{
+			name:          "not fail under the threshold",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           1,
+			failOver:      4,
+			want:          "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "summary of all issues",
+			args:          []string{"../../testdata/d.go", "../../testdata/b.go"},
+			minComplexity: 1,
+			top:           1,
+			summary:       true,
+			want:          "../../testdata/b.go:5:2: `if b1` has complex nested blocks (complexity: 9)\n2 files, 4 issues, max complexity 9, average 3.5\n",
+			code:          0,
+		},
+		{
+			name:          "summary in json",
+			outJSON:       true,
+			args:          []string{"../../testdata/nogo"},
+			minComplexity: 1,
+			top:           10,
+			summary:       true,
+			want:          "{\"issues\":null,\"summary\":{\"files\":0,\"issues\":0,\"maxComplexity\":0,\"avgComplexity\":0}}\n",
+			code:          0,
+		},
 		{
 			name:          "show only those with complexity of 2 or more",
 			args:          []string{"../../testdata/d.go"},
@@ -50,6 +245,192 @@ func TestRun(t *testing.T) {
 			want:          "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n",
 			code:          0,
 		},
+		{
+			name:          "min exceeds all found complexities",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1000,
+			top:           10,
+			want:          "warning: no issues with complexity 1000 or more; the highest complexity found is 3\n",
+			code:          0,
+		},
+		{
+			name:          "show only those within complexity range before top",
+			args:          []string{"../../testdata/d.go", "../../testdata/b.go"},
+			minComplexity: 1,
+			maxComplexity: 3,
+			top:           1,
+			want:          "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "report at the deepest if",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 2,
+			reportAt:      "deepest",
+			top:           10,
+			want:          "../../testdata/d.go:18:4: `if b1` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "sorted by file",
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			sortBy:        "file",
+			top:           3,
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/d.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/d.go:11:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "sorted in the order found",
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			sortBy:        "none",
+			top:           10,
+			want:          "../../testdata/d.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/d.go:11:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "invalid sort",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			sortBy:        "author",
+			top:           10,
+			want:          "invalid sort value: \"author\"\n",
+			code:          2,
+		},
+		{
+			name:          "invalid report-at",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			reportAt:      "middle",
+			top:           10,
+			want:          "invalid report-at value: \"middle\"\n",
+			code:          2,
+		},
+		{
+			name: "per-rule minimum given",
+			args: []string{"../../testdata/d.go"},
+			mins: []string{"1", "nested-if=2"},
+			top:  10,
+			want: "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code: 0,
+		},
+		{
+			name: "global minimum given after per-rule one",
+			args: []string{"../../testdata/d.go"},
+			mins: []string{"nested-if=3", "1"},
+			top:  10,
+			want: "../../testdata/d.go:16:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code: 0,
+		},
+		{
+			name: "unknown rule given to min",
+			args: []string{"../../testdata/d.go"},
+			mins: []string{"max-depth=4"},
+			top:  10,
+			want: "unknown rule for --min: \"max-depth\"\n",
+			code: 2,
+		},
+		{
+			name: "invalid min given",
+			args: []string{"../../testdata/d.go"},
+			mins: []string{"nested-if=x"},
+			top:  10,
+			want: "invalid --min value \"nested-if=x\": strconv.Atoi: parsing \"x\": invalid syntax\n",
+			code: 2,
+		},
+		{
+			name:          "sorted by complexity",
+			args:          []string{"../../testdata/topo/a", "../../testdata/topo/b"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/topo/a/a.go:8:2: `if b.B()` has complex nested blocks (complexity: 3)\n../../testdata/topo/b/b.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "sorted in dependency order",
+			args:          []string{"../../testdata/topo/a", "../../testdata/topo/b"},
+			minComplexity: 1,
+			top:           10,
+			topo:          true,
+			want:          "../../testdata/topo/b/b.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/topo/a/a.go:8:2: `if b.B()` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "sorted topologically in a module without a dot in its path",
+			args:          []string{"../../testdata/topomod/a", "../../testdata/topomod/b"},
+			minComplexity: 1,
+			top:           10,
+			topo:          true,
+			want:          "../../testdata/topomod/b/b.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/topomod/a/a.go:8:2: `if b.B()` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "flatness score",
+			args:          []string{"../../testdata/p.go"},
+			minComplexity: 1,
+			top:           10,
+			flatness:      true,
+			want:          "../../testdata/p.go:20:2: `if b1` has complex nested blocks (complexity: 1); consider inverting the nested condition into an early return\n../../testdata/p.go:26:2: `if b1` has complex nested blocks (complexity: 1)\nflatness score: 0.60 (guard clauses: 3, nested blocks: 2)\n",
+			code:          0,
+		},
+		{
+			name:          "flatness with a structured output",
+			args:          []string{"../../testdata/p.go"},
+			minComplexity: 1,
+			top:           10,
+			flatness:      true,
+			outSARIF:      true,
+			want:          "--flatness can't be given with --sarif\n",
+			code:          2,
+		},
+		{
+			name:          "issues reach the fail level",
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			failLevels:    []string{"nested-if=warning"},
+			want:          "../../testdata/b.go:5:2: `if b1` has complex nested blocks (complexity: 9)\nnested-if issues reached the fail level\n",
+			code:          1,
+		},
+		{
+			name:          "issues below the fail level",
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			failLevels:    []string{"nested-if=error"},
+			want:          "../../testdata/b.go:5:2: `if b1` has complex nested blocks (complexity: 9)\n",
+			code:          0,
+		},
+		{
+			name:          "issues reach the fail level by error complexity",
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			errorC:        1,
+			failLevels:    []string{"nested-if=error"},
+			want:          "../../testdata/b.go:5:2: `if b1` has complex nested blocks (complexity: 9)\nnested-if issues reached the fail level\n",
+			code:          1,
+		},
+		{
+			name:          "github actions with another output format",
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			outGitHub:     true,
+			outJSON:       true,
+			want:          "only one output format can be given, but got json, github-actions\n",
+			code:          2,
+		},
+		{
+			name:          "unknown rule given to fail-level",
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			failLevels:    []string{"max-depth=warning"},
+			want:          "unknown rule for --fail-level: \"max-depth\"\n",
+			code:          2,
+		},
 		{
 			name:          "ignore generated file",
 			args:          []string{"../../testdata/generated.go"},
@@ -58,6 +439,61 @@ func TestRun(t *testing.T) {
 			want:          "",
 			code:          0,
 		},
+		{
+			name:          "skip file larger than max file size",
+			verbose:       true,
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			maxFileSize:   100,
+			want:          "../../testdata/b.go is larger than 100 bytes\n",
+			code:          0,
+		},
+		{
+			name:          "file within max file size",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			maxFileSize:   1000,
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "average complexity exceeds max",
+			args:          []string{"../../testdata/k.go"},
+			minComplexity: 3,
+			top:           10,
+			maxAvg:        1.5,
+			want:          "../../testdata/k.go:6:2: `if b1` has complex nested blocks (complexity: 3)\naverage complexity 1.67 exceeds 1.50\n",
+			code:          1,
+		},
+		{
+			name:          "average complexity within max",
+			args:          []string{"../../testdata/k.go"},
+			minComplexity: 3,
+			top:           10,
+			maxAvg:        1.7,
+			want:          "../../testdata/k.go:6:2: `if b1` has complex nested blocks (complexity: 3)\n",
+			code:          0,
+		},
+		{
+			name:          "include generated file",
+			args:          []string{"../../testdata/generated.go"},
+			minComplexity: 1,
+			top:           10,
+			includeGen:    true,
+			want:          "../../testdata/generated.go:10:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "only generated file",
+			args:          []string{"../../testdata/generated.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			onlyGen:       true,
+			want:          "../../testdata/generated.go:10:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
 		{
 			name:          "directory given",
 			args:          []string{"../../testdata/a"},
@@ -76,20 +512,29 @@ func TestRun(t *testing.T) {
 		},
 		{
 			name:          "Check all files recursively",
-			verbose:       true,
+			dir:           "../../testdata/a",
 			args:          []string{"./..."},
 			minComplexity: 1,
 			top:           10,
-			want:          "",
+			want:          "a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\nb/a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n",
 			code:          0,
 		},
 		{
 			name:          "no args given",
-			verbose:       true,
+			dir:           "../../testdata/a",
 			args:          []string{},
 			minComplexity: 1,
 			top:           10,
-			want:          "",
+			want:          "a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\nb/a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "current directory given",
+			dir:           "../../testdata/a",
+			args:          []string{"."},
+			minComplexity: 1,
+			top:           10,
+			want:          "a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n",
 			code:          0,
 		},
 		{
@@ -103,13 +548,368 @@ func TestRun(t *testing.T) {
 			}(),
 			code: 0,
 		},
+		{
+			name:          "files behind build tags skipped",
+			args:          []string{"../../testdata/tags"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/tags/a.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "build tags given",
+			args:          []string{"../../testdata/tags"},
+			minComplexity: 1,
+			top:           10,
+			buildTags:     []string{"integration"},
+			want:          "../../testdata/tags/integration.go:9:2: `if b1` has complex nested blocks (complexity: 3)\n../../testdata/tags/a.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "stdin given",
+			args:          []string{"-"},
+			minComplexity: 1,
+			top:           10,
+			stdin:         "package main\n\nfunc main() {\n\tif a {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want:          "<stdin>:4:2: `if a` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "file failed to parse",
+			args:          []string{"-", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			stdin:         "package main\n\nfunc main() {\n\tif {\n",
+			want:          "<stdin>:4:5: missing condition in if statement\n../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\nfailed to parse 1 file\n",
+			code:          3,
+		},
+		{
+			name:          "invalid file",
+			args:          []string{"../../testdata/invalid.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/invalid.go:4:5: missing condition in if statement\nfailed to parse 1 file\n",
+			code:          3,
+		},
+		{
+			name:          "stdin given with file path",
+			args:          []string{"-"},
+			minComplexity: 1,
+			top:           10,
+			stdin:         "package main\n\nfunc main() {\n\tif a {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			stdinPath:     "cmd/app/main.go",
+			want:          "cmd/app/main.go:4:2: `if a` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "stdin given with json output",
+			outJSON:       true,
+			args:          []string{"-"},
+			minComplexity: 1,
+			top:           10,
+			stdin:         "package main\n\nfunc main() {\n\tif a {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want:          "[{\"Pos\":{\"Filename\":\"\\u003cstdin\\u003e\",\"Offset\":29,\"Line\":4,\"Column\":2},\"EndPos\":{\"Filename\":\"\\u003cstdin\\u003e\",\"Offset\":51,\"Line\":7,\"Column\":3},\"Complexity\":1,\"Message\":\"`if a` has complex nested blocks (complexity: 1)\",\"Condition\":\"a\",\"Path\":\"if \\u003e if\",\"RelLine\":1,\"FuncName\":\"main\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":1,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}}]\n",
+			code:          0,
+		},
 		{
 			name:          "json output",
 			outJSON:       true,
 			args:          []string{"../../testdata/a.go"},
 			minComplexity: 1,
 			top:           10,
-			want:          "[{\"Pos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":78,\"Line\":9,\"Column\":2},\"Complexity\":1,\"Message\":\"`if b1` has complex nested blocks (complexity: 1)\"}]\n",
+			want:          "[{\"Pos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":78,\"Line\":9,\"Column\":2},\"EndPos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":125,\"Line\":12,\"Column\":3},\"Complexity\":1,\"Message\":\"`if b1` has complex nested blocks (complexity: 1)\",\"Condition\":\"b1\",\"Path\":\"if \\u003e if\",\"RelLine\":6,\"FuncName\":\"_\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":1,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}}]\n",
+			code:          0,
+		},
+		{
+			name:          "json lines output",
+			outJSONL:      true,
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 3,
+			top:           10,
+			want:          "{\"Pos\":{\"Filename\":\"../../testdata/d.go\",\"Offset\":152,\"Line\":16,\"Column\":2},\"EndPos\":{\"Filename\":\"../../testdata/d.go\",\"Offset\":221,\"Line\":21,\"Column\":3},\"Complexity\":3,\"Message\":\"`if b1` has complex nested blocks (complexity: 3)\",\"Condition\":\"b1\",\"Path\":\"if \\u003e if \\u003e if\",\"RelLine\":13,\"FuncName\":\"_\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":3,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}}\n",
+			code:          0,
+		},
+		{
+			name:          "junit output",
+			outJUnit:      true,
+			args:          []string{"../../testdata/e.go"},
+			minComplexity: 1,
+			top:           10,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="nestif" tests="1" failures="1">
+    <testcase name="../../testdata/e.go:7">
+      <failure message="` + "`if n &lt; 10 &amp;&amp; b1` has complex nested blocks (complexity: 1)" + `"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+			code: 0,
+		},
+		{
+			name:          "checkstyle output",
+			outCheckstyle: true,
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go"},
+			minComplexity: 2,
+			top:           10,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+  <file name="../../testdata/d.go">
+    <error line="16" column="2" severity="warning" message="` + "`if b1` has complex nested blocks (complexity: 3)" + `" source="nestif"></error>
+  </file>
+</checkstyle>
+`,
+			code: 0,
+		},
+		{
+			name:          "checkstyle output grouped by file",
+			outCheckstyle: true,
+			args:          []string{"../../testdata/m.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+  <file name="../../testdata/a.go">
+    <error line="9" column="2" severity="warning" message="` + "`if b1` has complex nested blocks (complexity: 1)" + `" source="nestif"></error>
+  </file>
+  <file name="../../testdata/m.go">
+    <error line="9" column="2" severity="warning" message="` + "`if flags.enabled` has complex nested blocks (complexity: 1)" + `" source="nestif"></error>
+    <error line="14" column="2" severity="warning" message="` + "`if b1` has complex nested blocks (complexity: 1)" + `" source="nestif"></error>
+  </file>
+</checkstyle>
+`,
+			code: 0,
+		},
+		{
+			name:          "sarif output",
+			outSARIF:      true,
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          `{"version":"2.1.0","$schema":"https://json.schemastore.org/sarif-2.1.0.json","runs":[{"tool":{"driver":{"name":"nestif","informationUri":"https://github.com/nakabonne/nestif","rules":[{"id":"nested-if","shortDescription":{"text":"Reports complex nested if statements"}}]}},"results":[{"ruleId":"nested-if","level":"warning","message":{"text":"` + "`if b1` has complex nested blocks (complexity: 1)" + `"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"../../testdata/a.go"},"region":{"startLine":9,"startColumn":2,"endLine":12,"endColumn":3}}}],"properties":{"complexity":1}}]}]}` + "\n",
+			code:          0,
+		},
+		{
+			name:          "sonar output",
+			outSonar:      true,
+			args:          []string{"../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "{\"issues\":[{\"engineId\":\"nestif\",\"ruleId\":\"nested-if\",\"severity\":\"MAJOR\",\"type\":\"CODE_SMELL\",\"primaryLocation\":{\"message\":\"`if b1` has complex nested blocks (complexity: 9)\",\"filePath\":\"../../testdata/b.go\",\"textRange\":{\"startLine\":5,\"startColumn\":1}}}]}\n",
+			code:          0,
+		},
+		{
+			name:          "annotated output",
+			annotate:      true,
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want: `// ../../testdata/a.go
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 0
+	}
+
+	// nestif: complexity 1
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
+`,
+			code: 0,
+		},
+		{
+			name:          "offsets output",
+			offsets:       true,
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/a.go:78: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "brief output",
+			brief:         true,
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/a.go: 1 issue [1]\n../../testdata/d.go: 3 issues [3,1,1]\n",
+			code:          0,
+		},
+		{
+			name:          "brief output sorted by file",
+			brief:         true,
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           10,
+			sortBy:        "file",
+			want:          "../../testdata/d.go: 3 issues [3,1,1]\n",
+			code:          0,
+		},
+		{
+			name:          "benchfmt output",
+			benchfmt:      true,
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go", "../../testdata/a"},
+			minComplexity: 1,
+			top:           10,
+			want:          "BenchmarkNestif/../../testdata 1 6 complexity 4 issues\nBenchmarkNestif/../../testdata/a 1 1 complexity 1 issues\n",
+			code:          0,
+		},
+		{
+			name:          "source shown",
+			args:          []string{"../../testdata/b.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			showSource:    true,
+			want: "../../testdata/b.go:5:2: `if b1` has complex nested blocks (complexity: 9)\n" +
+				"       5 | \tif b1 { // complexity: 9\n" +
+				"       6 | \t\tif b2 { // +1\n" +
+				"       7 | \t\t\tif b3 { // +2\n" +
+				"       8 | \t\t\t}\n" +
+				"       9 | \t\t}\n" +
+				"      10 | \n" +
+				"      11 | \t\tif b2 { // +1\n" +
+				"      12 | \t\t\tif b3 { // +2\n" +
+				"      13 | \t\t\t\tif b4 { // +3\n" +
+				"      14 | \t\t\t\t}\n" +
+				"         | ...\n" +
+				"../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n" +
+				"       9 | \tif b1 { // complexity: 1\n" +
+				"      10 | \t\tif b2 { // +1\n" +
+				"      11 | \t\t}\n" +
+				"      12 | \t}\n",
+			code: 0,
+		},
+		{
+			name:          "relative paths",
+			args:          []string{"../../testdata/../testdata/a.go", "-"},
+			minComplexity: 1,
+			top:           10,
+			relative:      true,
+			stdin:         "package main\n\nfunc main() {\n\tif a {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n<stdin>:4:2: `if a` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "both relative and absolute paths",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			relative:      true,
+			absolute:      true,
+			want:          "--relative and --abs can't be given together\n",
+			code:          2,
+		},
+		{
+			name:          "columns with tab width in editorconfig",
+			editorConfig:  true,
+			args:          []string{"../../testdata/editorconfig/a.go", "../../testdata/editorconfig/sub/a.go", "../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/editorconfig/a.go:9:5: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/editorconfig/sub/a.go:9:9: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "histogram output",
+			histogram:     true,
+			args:          []string{"../../testdata/d.go", "../../testdata/a.go", "../../testdata/b.go", "../../testdata/c.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "1-2:   3 ###\n3-5:   3 ###\n6+:    1 #\n",
+			code:          0,
+		},
+		{
+			name:          "by-dir output",
+			byDir:         true,
+			args:          []string{"../../testdata/a", "../../testdata/d.go", "../../testdata/b.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata: issues: 4, total complexity: 14, max complexity: 9\n../../testdata/a: issues: 1, total complexity: 1, max complexity: 1\n",
+			code:          0,
+		},
+		{
+			name:          "table format",
+			format:        "table",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           1,
+			want:          "FILE                 LINE  COMPLEXITY  CONDITION\n../../testdata/d.go  16    3           b1\n",
+			code:          0,
+		},
+		{
+			name:          "format conflicting with an output format flag",
+			format:        "table",
+			brief:         true,
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "only one output format can be given, but got table, brief\n",
+			code:          2,
+		},
+		{
+			name:          "invalid format",
+			format:        "yaml",
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "invalid format value: \"yaml\"\n",
+			code:          2,
+		},
+		{
+			name:          "table output",
+			table:         true,
+			args:          []string{"-", "../../testdata/d.go"},
+			minComplexity: 1,
+			top:           4,
+			stdin:         "package main\n\nfunc main() {\n\tif aaaaaaaaaa && bbbbbbbbbb && cccccccccc && dddddddddd {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want: "FILE                 LINE  COMPLEXITY  CONDITION\n" +
+				"../../testdata/d.go  16    3           b1\n" +
+				"../../testdata/d.go  6     1           b1\n" +
+				"../../testdata/d.go  11    1           b1\n" +
+				"<stdin>              4     1           aaaaaaaaaa && bbbbbbbbbb && ccccccccc...\n",
+			code: 0,
+		},
+		{
+			name:          "package embedding files",
+			args:          []string{"../../testdata/embed"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/embed/embed.go:13:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+
+		{
+			name:          "checkstyle output with severities",
+			outCheckstyle: true,
+			args:          []string{"../../testdata/d.go"},
+			minComplexity: 1,
+			top:           10,
+			warnC:         2,
+			errorC:        3,
+			want:          "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<checkstyle version=\"5.0\">\n  <file name=\"../../testdata/d.go\">\n    <error line=\"16\" column=\"2\" severity=\"error\" message=\"`if b1` has complex nested blocks (complexity: 3)\" source=\"nestif\"></error>\n    <error line=\"6\" column=\"2\" severity=\"info\" message=\"`if b1` has complex nested blocks (complexity: 1)\" source=\"nestif\"></error>\n    <error line=\"11\" column=\"2\" severity=\"info\" message=\"`if b1` has complex nested blocks (complexity: 1)\" source=\"nestif\"></error>\n  </file>\n</checkstyle>\n",
+			code:          0,
+		},
+		{
+			name:          "github actions output",
+			outGitHub:     true,
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			want:          "::warning file=../../testdata/a.go,line=9,col=2::`if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "github actions output with escapes",
+			outGitHub:     true,
+			args:          []string{"-"},
+			minComplexity: 1,
+			top:           10,
+			stdin:         "package main\n\nfunc main() {\n\tif a%2 == 0 {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want:          "::warning file=<stdin>,line=4,col=2::`if a%252 == 0` has complex nested blocks (complexity: 1)\n",
 			code:          0,
 		},
 		{
@@ -128,21 +928,131 @@ func TestRun(t *testing.T) {
 			top:           10,
 			excludeDirs:   []string{"(^|/../../testdata"},
 			want:          "failed to parse exclude dir pattern: error parsing regexp: missing closing ): `(^|/../../testdata`\n",
-			code:          1,
+			code:          2,
+		},
+		{
+			name:          "package of another module",
+			args:          []string{"../../testdata/external"},
+			minComplexity: 1,
+			top:           10,
+			want:          "../../testdata/external/external.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "skip-external given",
+			args:          []string{"../../testdata/external", "../../testdata/a"},
+			minComplexity: 1,
+			top:           10,
+			skipExternal:  true,
+			want:          "../../testdata/a/a.go:8:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "exclude-files given",
+			args:          []string{"../../testdata/a.go", "../../testdata/d.go", "../../testdata/a"},
+			minComplexity: 1,
+			top:           10,
+			excludeDirs:   []string{"testdata/a$"},
+			excludeFiles:  []string{`d\.go$`},
+			want:          "../../testdata/a.go:9:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "wrong exclude-files given",
+			args:          []string{"../../testdata/a.go"},
+			minComplexity: 1,
+			top:           10,
+			excludeFiles:  []string{`(a`},
+			want:          "failed to parse exclude file pattern: error parsing regexp: missing closing ): `(a`\n",
+			code:          2,
+		},
+		{
+			name:          "exclude-cond given",
+			args:          []string{"../../testdata/m.go"},
+			minComplexity: 1,
+			top:           10,
+			excludeConds:  []string{`flags\.`},
+			want:          "../../testdata/m.go:14:2: `if b1` has complex nested blocks (complexity: 1)\n",
+			code:          0,
+		},
+		{
+			name:          "wrong exclude-cond given",
+			args:          []string{"../../testdata/m.go"},
+			minComplexity: 1,
+			top:           10,
+			excludeConds:  []string{`flags\.(`},
+			want:          "failed to parse exclude cond pattern: error parsing regexp: missing closing ): `flags\\.(`\n",
+			code:          2,
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			if tc.dir != "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chdir(tc.dir); err != nil {
+					t.Fatal(err)
+				}
+				defer os.Chdir(wd)
+			}
 			b := new(bytes.Buffer)
 			a := app{
-				verbose:       tc.verbose,
-				outJSON:       tc.outJSON,
-				minComplexity: tc.minComplexity,
-				top:           tc.top,
-				excludeDirs:   tc.excludeDirs,
-				stdout:        b,
-				stderr:        b,
+				verbose:          tc.verbose,
+				quiet:            tc.quiet,
+				outJSON:          tc.outJSON,
+				outJSONL:         tc.outJSONL,
+				outJUnit:         tc.outJUnit,
+				outSonar:         tc.outSonar,
+				outCheckstyle:    tc.outCheckstyle,
+				outSARIF:         tc.outSARIF,
+				outGitHub:        tc.outGitHub,
+				outLSP:           tc.outLSP,
+				annotate:         tc.annotate,
+				brief:            tc.brief,
+				topo:             tc.topo,
+				flatness:         tc.flatness,
+				offsets:          tc.offsets,
+				editorConfig:     tc.editorConfig,
+				showSource:       tc.showSource,
+				relative:         tc.relative,
+				absolute:         tc.absolute,
+				benchfmt:         tc.benchfmt,
+				histogram:        tc.histogram,
+				byDir:            tc.byDir,
+				table:            tc.table,
+				format:           tc.format,
+				summary:          tc.summary,
+				checkOnly:        tc.checkOnly,
+				listFiles:        tc.listFiles,
+				failOnIssues:     tc.failOnIssues,
+				failOver:         tc.failOver,
+				warnComplexity:   tc.warnC,
+				errorComplexity:  tc.errorC,
+				minComplexity:    tc.minComplexity,
+				mins:             tc.mins,
+				failLevels:       tc.failLevels,
+				maxComplexity:    tc.maxComplexity,
+				minLines:         tc.minLines,
+				reportAt:         tc.reportAt,
+				sortBy:           tc.sortBy,
+				top:              tc.top,
+				topPerFile:       tc.topPerFile,
+				maxFileSize:      tc.maxFileSize,
+				includeGenerated: tc.includeGen,
+				onlyGenerated:    tc.onlyGen,
+				maxAvg:           tc.maxAvg,
+				excludeDirs:      tc.excludeDirs,
+				excludeFiles:     tc.excludeFiles,
+				excludeConds:     tc.excludeConds,
+				skipExternal:     tc.skipExternal,
+				buildTags:        tc.buildTags,
+				stdin:            strings.NewReader(tc.stdin),
+				stdinPath:        tc.stdinPath,
+				stdout:           b,
+				stderr:           b,
 			}
 			c := a.run(tc.args)
 			assert.Equal(t, tc.code, c)
@@ -150,3 +1060,128 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestRunAbs(t *testing.T) {
+	abs, err := filepath.Abs("../../testdata/a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		absolute:      true,
+		stdout:        b,
+		stderr:        b,
+	}
+	c := a.run([]string{"../../testdata/a.go"})
+	assert.Equal(t, 0, c)
+	assert.Equal(t, abs+":9:2: `if b1` has complex nested blocks (complexity: 1)\n", b.String())
+}
+
+func TestRunFlatnessJSON(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	a := app{
+		outJSON:       true,
+		flatness:      true,
+		minComplexity: 1,
+		top:           10,
+		stdout:        stdout,
+		stderr:        stderr,
+	}
+	assert.Equal(t, 0, a.run([]string{"../../testdata/p.go"}))
+	var report struct {
+		Issues   []nestif.Issue `json:"issues"`
+		Flatness flatness       `json:"flatness"`
+	}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Len(t, report.Issues, 2)
+	assert.Equal(t, flatness{Score: 0.6, Guards: 3, Nested: 2}, report.Flatness)
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunPackageNotFound(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	a := app{
+		outJSON:       true,
+		quiet:         true,
+		minComplexity: 1,
+		top:           10,
+		stdout:        stdout,
+		stderr:        stderr,
+	}
+	assert.Equal(t, 3, a.run([]string{"notexist", "../../testdata/a.go"}))
+	var issues []nestif.Issue
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &issues))
+	assert.Len(t, issues, 1)
+	assert.Contains(t, stderr.String(), "notexist")
+}
+
+func TestRunOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nestif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name   string
+		output string
+		want   string
+		stderr string
+		code   int
+	}{
+		{
+			name:   "output file",
+			output: filepath.Join(dir, "report.json"),
+			want:   "[{\"Pos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":78,\"Line\":9,\"Column\":2},\"EndPos\":{\"Filename\":\"../../testdata/a.go\",\"Offset\":125,\"Line\":12,\"Column\":3},\"Complexity\":1,\"Message\":\"`if b1` has complex nested blocks (complexity: 1)\",\"Condition\":\"b1\",\"Path\":\"if \\u003e if\",\"RelLine\":6,\"FuncName\":\"_\",\"Severity\":\"warning\",\"Invertible\":false,\"Breakdown\":{\"Nesting\":1,\"Constructs\":0,\"Else\":0,\"ElseIf\":0,\"Negations\":0,\"BooleanOps\":0,\"InitClauses\":0,\"Labels\":0}}]\n",
+			code:   0,
+		},
+		{
+			name:   "directory not found",
+			output: filepath.Join(dir, "not-found", "report.json"),
+			stderr: "failed to create the output file: open " + filepath.Join(dir, "not-found", "report.json") + ": no such file or directory\n",
+			code:   3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			a := app{
+				outJSON:       true,
+				minComplexity: 1,
+				top:           10,
+				output:        tc.output,
+				stdout:        stdout,
+				stderr:        stderr,
+			}
+			assert.Equal(t, tc.code, a.run([]string{"../../testdata/a.go"}))
+			assert.Empty(t, stdout.String())
+			assert.Equal(t, tc.stderr, stderr.String())
+			if tc.code == 0 {
+				b, err := ioutil.ReadFile(tc.output)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, string(b))
+			}
+		})
+	}
+}
+
+func TestSortIssues(t *testing.T) {
+	// Enough issues of the same complexity in reverse order that an unstable
+	// sort by complexity alone would shuffle them.
+	var issues, want []nestif.Issue
+	for i := 0; i < 50; i++ {
+		pos := token.Position{Filename: fmt.Sprintf("%02d.go", i/10), Line: i%10/2 + 1, Column: i%2 + 1}
+		want = append(want, nestif.Issue{Pos: pos, Complexity: 1})
+	}
+	want = append([]nestif.Issue{{Pos: token.Position{Filename: "99.go", Line: 1, Column: 1}, Complexity: 2}}, want...)
+	for i := len(want) - 1; i >= 0; i-- {
+		issues = append(issues, want[i])
+	}
+
+	a := app{}
+	a.sortIssues(issues)
+	assert.Equal(t, want, issues)
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// pkgDir is a directory of a package with the files to be checked in it.
+type pkgDir struct {
+	// Directory as shown in the file paths of issues.
+	Dir string
+	// File names in Dir, including the test files of the package itself.
+	Files []string
+}
+
+// loadPackages finds the packages matching the pattern with go/packages, so that
+// they are resolved just like the go command does, with modules, vendoring and
+// replace directives taken into account. The pattern is relative to dir, which
+// is the working directory if empty.
+// Packages with no Go files are left out. So are external test packages, whose
+// files aren't in the package being checked.
+func (a *app) loadPackages(dir, pattern string) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports,
+		Dir:   dir,
+		Tests: true,
+	}
+	if len(a.buildTags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(a.buildTags, ",")}
+	}
+	// Directories outside modules are found in GOPATH mode, as they used to be.
+	wd := dir
+	if wd == "" {
+		wd = "."
+	}
+	if abs, err := filepath.Abs(wd); err == nil && moduleRoot(abs) == "" {
+		cfg.Env = append(os.Environ(), "GO111MODULE=off")
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", pattern, err)
+	}
+
+	var found []*packages.Package
+	for _, pkg := range pkgs {
+		// The test binary, with its generated main file.
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		if strings.HasSuffix(pkg.PkgPath, "_test") {
+			continue
+		}
+		if len(pkg.GoFiles) == 0 {
+			for _, e := range pkg.Errors {
+				if !isNoGoError(e) {
+					return nil, e
+				}
+			}
+			continue
+		}
+		for _, e := range pkg.Errors {
+			a.debug(e)
+		}
+		a.recordImports(pkg)
+		found = append(found, pkg)
+	}
+	return found, nil
+}
+
+// isNoGoError reports whether the error is due to no Go source files,
+// which isn't worth complaining about.
+func isNoGoError(err packages.Error) bool {
+	return strings.Contains(err.Msg, "no Go files") ||
+		strings.Contains(err.Msg, "build constraints exclude all Go files")
+}
+
+// pkgDirs groups the files of the packages by directory, in the order found.
+// The test variant of a package has the files of the package itself as well,
+// so files are listed only once. name gives the directory to be shown from
+// the absolute one.
+func pkgDirs(pkgs []*packages.Package, name func(dir string) string) []pkgDir {
+	var dirs []pkgDir
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.GoFiles {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			dir := filepath.Dir(f)
+			i, ok := index[dir]
+			if !ok {
+				i = len(dirs)
+				index[dir] = i
+				dirs = append(dirs, pkgDir{Dir: name(dir)})
+			}
+			dirs[i].Files = append(dirs[i].Files, filepath.Base(f))
+		}
+	}
+	return dirs
+}
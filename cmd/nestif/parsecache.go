@@ -0,0 +1,128 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nakabonne/nestif"
+)
+
+// parsedFile is a file read and parsed before, reused for the same file
+// reached again, like through both a directory and a package given.
+// Only files reached more than once are kept, until their last check.
+type parsedFile struct {
+	modTime time.Time
+	src     []byte
+	// Nil until parsed.
+	fset *token.FileSet
+	file *ast.File
+}
+
+// cacheKey gives the absolute path of the file, so that different paths
+// to the same file share the cache.
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// countRefs counts how many times each file is to be checked, from the files
+// and package directories given.
+func (a *app) countRefs(files []string, dirs []pkgDir) {
+	a.refs = make(map[string]int)
+	for _, f := range files {
+		a.refs[cacheKey(f)]++
+	}
+	for _, d := range dirs {
+		for _, f := range d.Files {
+			a.refs[cacheKey(filepath.Join(d.Dir, f))]++
+		}
+	}
+}
+
+// release drops the file from the cache once it's been checked as many
+// times as it's reached.
+func (a *app) release(path string) {
+	key := cacheKey(path)
+	if a.refs[key] > 1 {
+		a.refs[key]--
+		return
+	}
+	delete(a.refs, key)
+	delete(a.parsed, key)
+}
+
+// readFile reads the file at path, reusing the source read before unless
+// the file has been modified since.
+func (a *app) readFile(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(path)
+	if e, ok := a.parsed[key]; ok && e.modTime.Equal(fi.ModTime()) {
+		return e.src, nil
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if a.refs[key] > 1 {
+		if a.parsed == nil {
+			a.parsed = make(map[string]*parsedFile)
+		}
+		a.parsed[key] = &parsedFile{modTime: fi.ModTime(), src: src}
+	}
+	return src, nil
+}
+
+// parse parses src of the file at path, reusing the syntax tree parsed before
+// from the same source. The tree is kept only if the source is, by readFile.
+// Note that the positions in a reused tree are of the path it was first parsed with.
+func (a *app) parse(path string, src []byte) (*token.FileSet, *ast.File, error) {
+	key := cacheKey(path)
+	e, ok := a.parsed[key]
+	if ok && e.file != nil && bytes.Equal(e.src, src) {
+		return e.fset, e.file, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok && bytes.Equal(e.src, src) {
+		e.fset, e.file = fset, f
+	}
+	return fset, f, nil
+}
+
+// renamePositions changes the file name of the positions from old to path,
+// leaving the ones moved elsewhere by line directives as they are.
+func renamePositions(issues []nestif.Issue, funcs []nestif.FuncComplexity, old, path string) {
+	for i := range issues {
+		if issues[i].Pos.Filename == old {
+			issues[i].Pos.Filename = path
+		}
+		if issues[i].EndPos.Filename == old {
+			issues[i].EndPos.Filename = path
+		}
+	}
+	for i := range funcs {
+		if funcs[i].Pos.Filename == old {
+			funcs[i].Pos.Filename = path
+		}
+	}
+}
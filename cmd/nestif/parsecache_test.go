@@ -0,0 +1,62 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nakabonne/nestif"
+)
+
+func TestRunParsesOnce(t *testing.T) {
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		sortBy:        "none",
+		stdout:        b,
+		stderr:        b,
+	}
+	// The same file reached through a directory and two different paths.
+	c := a.run([]string{"../../testdata/tags", "../../testdata/tags/a.go", "../../testdata/../testdata/tags/a.go"})
+	assert.Equal(t, 0, c)
+	assert.Equal(t, "../../testdata/tags/a.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/../testdata/tags/a.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n../../testdata/tags/a.go:6:2: `if b1` has complex nested blocks (complexity: 1)\n", b.String())
+	// Dropped after the last check.
+	assert.Empty(t, a.parsed)
+}
+
+func TestParseCacheRefs(t *testing.T) {
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		stdout:        b,
+		stderr:        b,
+	}
+	checker := &nestif.Checker{MinComplexity: 1}
+	a.countRefs([]string{"../../testdata/a.go", "../../testdata/../testdata/tags/a.go"}, []pkgDir{
+		{Dir: "../../testdata/tags", Files: []string{"a.go"}},
+	})
+
+	// Reached once, so not kept.
+	_, err := a.checkFile(checker, "../../testdata/a.go")
+	assert.NoError(t, err)
+	assert.Empty(t, a.parsed)
+
+	_, err = a.checkFile(checker, "../../testdata/tags/a.go")
+	assert.NoError(t, err)
+	assert.Len(t, a.parsed, 1)
+	for _, e := range a.parsed {
+		assert.NotNil(t, e.file)
+	}
+	_, err = a.checkFile(checker, "../../testdata/../testdata/tags/a.go")
+	assert.NoError(t, err)
+	assert.Empty(t, a.parsed)
+	assert.Empty(t, a.refs)
+}
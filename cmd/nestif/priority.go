@@ -0,0 +1,116 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/nakabonne/nestif"
+)
+
+// churner tells how often a file changes.
+type churner interface {
+	// Commits returns the number of commits that touched the file.
+	Commits(filename string) (int, error)
+}
+
+// gitChurner is a churner backed by the git command.
+type gitChurner struct{}
+
+func (gitChurner) Commits(filename string) (int, error) {
+	dir, base := filepath.Split(filename)
+	cmd := exec.Command("git", "log", "--follow", "--format=%H", "--", base)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to run git log on %s: %v", filename, err)
+	}
+	return bytes.Count(out, []byte("\n")), nil
+}
+
+// priority is the score of an issue telling how worth fixing it is.
+type priority struct {
+	issue     nestif.Issue
+	funcLines int
+	commits   int
+	score     int
+}
+
+// writePriority prints issues ranked by the product of their complexity, the
+// number of lines of the enclosing function and the number of commits to the
+// file plus one, so that big, messy, frequently changed functions come first.
+// Files whose commits can't be counted, e.g. ones not under git, count as
+// having none.
+func (a *app) writePriority(issues []nestif.Issue) {
+	files := make(map[string]*ast.File)
+	fsets := make(map[string]*token.FileSet)
+	commits := make(map[string]int)
+	list := make([]priority, 0, len(issues))
+	for _, issue := range issues {
+		name := issue.Pos.Filename
+		if _, ok := fsets[name]; !ok {
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				a.debug(err)
+			}
+			files[name], fsets[name] = f, fset
+
+			n, err := a.churner.Commits(name)
+			if err != nil {
+				a.debug(err)
+			}
+			commits[name] = n
+		}
+		p := priority{
+			issue:     issue,
+			funcLines: funcLines(files[name], fsets[name], issue.Pos.Line),
+			commits:   commits[name],
+		}
+		p.score = issue.Complexity * p.funcLines * (p.commits + 1)
+		list = append(list, p)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].score > list[j].score
+	})
+	for i, p := range list {
+		if i >= a.top {
+			return
+		}
+		fmt.Fprintf(a.stdout, "%s:%d:%d: priority %d (complexity: %d, function lines: %d, commits: %d)\n",
+			p.issue.Pos.Filename, p.issue.Pos.Line, p.issue.Pos.Column, p.score, p.issue.Complexity, p.funcLines, p.commits)
+	}
+}
+
+// funcLines returns the number of lines of the function declaration containing
+// the given line, or 1 if there is none.
+func funcLines(f *ast.File, fset *token.FileSet, line int) int {
+	if f == nil {
+		return 1
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+		if start <= line && line <= end {
+			return end - start + 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChurner struct {
+	// commits maps a file to the number of commits to it.
+	commits map[string]int
+}
+
+func (f *fakeChurner) Commits(filename string) (int, error) {
+	n, ok := f.commits[filename]
+	if !ok {
+		return 0, fmt.Errorf("%s is not under git", filename)
+	}
+	return n, nil
+}
+
+func TestRunPriority(t *testing.T) {
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		priority:      true,
+		churner: &fakeChurner{
+			commits: map[string]int{
+				"../../testdata/a.go": 9,
+			},
+		},
+		stdout: b,
+		stderr: b,
+	}
+	c := a.run([]string{"../../testdata/d.go", "../../testdata/a.go"})
+	assert.Equal(t, 0, c)
+	assert.Equal(t, `../../testdata/a.go:9:2: priority 110 (complexity: 1, function lines: 11, commits: 9)
+../../testdata/d.go:16:2: priority 60 (complexity: 3, function lines: 20, commits: 0)
+../../testdata/d.go:6:2: priority 20 (complexity: 1, function lines: 20, commits: 0)
+../../testdata/d.go:11:2: priority 20 (complexity: 1, function lines: 20, commits: 0)
+`, b.String())
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiles starts the CPU profile if --cpuprofile is given. The returned
+// function stops it, and writes the heap profile if --memprofile is given.
+func (a *app) startProfiles() (stop func(), err error) {
+	var cpu *os.File
+	if a.cpuProfile != "" {
+		cpu, err = os.Create(a.cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if a.memProfile != "" {
+			if err := writeHeapProfile(a.memProfile); err != nil {
+				fmt.Fprintln(a.stderr, err)
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Get up-to-date statistics.
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nestif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := new(bytes.Buffer)
+	a := app{
+		minComplexity: 1,
+		top:           10,
+		cpuProfile:    filepath.Join(dir, "cpu.prof"),
+		memProfile:    filepath.Join(dir, "mem.prof"),
+		stdout:        b,
+		stderr:        b,
+	}
+	stop, err := a.startProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, a.run([]string{"../../testdata/a.go"}))
+	stop()
+
+	for _, name := range []string{"cpu.prof", "mem.prof"} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if assert.NoError(t, err) {
+			assert.NotZero(t, fi.Size())
+		}
+	}
+}
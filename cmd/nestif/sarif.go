@@ -0,0 +1,135 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/nakabonne/nestif"
+)
+
+// sarifLog is the root of a SARIF 2.1.0 log.
+// See: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifProperties struct {
+	Complexity int `json:"complexity"`
+}
+
+// sarifJSON converts issues into a SARIF 2.1.0 log with a single run.
+func sarifJSON(issues []nestif.Issue) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "nestif",
+				InformationURI: "https://github.com/nakabonne/nestif",
+				Rules: []sarifRule{
+					{
+						ID:               ruleNestedIf,
+						ShortDescription: sarifMessage{Text: "Reports complex nested if statements"},
+					},
+				},
+			},
+		},
+		Results: make([]sarifResult, 0, len(issues)),
+	}
+	for _, issue := range issues {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  ruleNestedIf,
+			Level:   sarifLevel(issue.Severity),
+			Message: sarifMessage{Text: issue.Message},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						// Relative paths are emitted as-is so that they resolve against the repository root.
+						ArtifactLocation: sarifArtifactLocation{URI: issue.Pos.Filename},
+						Region: sarifRegion{
+							StartLine:   issue.Pos.Line,
+							StartColumn: issue.Pos.Column,
+							EndLine:     issue.EndPos.Line,
+							EndColumn:   issue.EndPos.Column,
+						},
+					},
+				},
+			},
+			Properties: sarifProperties{Complexity: issue.Complexity},
+		})
+	}
+	return json.Marshal(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLevel maps the severity of an issue to a SARIF result level.
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error":
+		return "error"
+	case "info":
+		return "note"
+	default:
+		return "warning"
+	}
+}
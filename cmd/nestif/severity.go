@@ -0,0 +1,47 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type severity int
+
+const (
+	severityInfo severity = iota
+	severityWarning
+	severityError
+)
+
+var severityNames = map[string]severity{
+	"info":    severityInfo,
+	"warning": severityWarning,
+	"error":   severityError,
+}
+
+// parseFailLevels parses the --fail-level values, each of which is rule=severity.
+func parseFailLevels(values []string) (map[string]severity, error) {
+	levels := make(map[string]severity, len(values))
+	for _, v := range values {
+		i := strings.Index(v, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid --fail-level value %q: must be rule=severity", v)
+		}
+		rule, name := v[:i], v[i+1:]
+		if !knownRules[rule] {
+			return nil, fmt.Errorf("unknown rule for --fail-level: %q", rule)
+		}
+		s, ok := severityNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown severity for --fail-level: %q", name)
+		}
+		levels[rule] = s
+	}
+	return levels, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/nakabonne/nestif"
+)
+
+// sonarReport is the SonarQube generic issue import format.
+// See: https://docs.sonarqube.org/latest/analysis/generic-issue/
+type sonarReport struct {
+	Issues []sonarIssue `json:"issues"`
+}
+
+type sonarIssue struct {
+	EngineID        string        `json:"engineId"`
+	RuleID          string        `json:"ruleId"`
+	Severity        string        `json:"severity"`
+	Type            string        `json:"type"`
+	PrimaryLocation sonarLocation `json:"primaryLocation"`
+}
+
+type sonarLocation struct {
+	Message   string         `json:"message"`
+	FilePath  string         `json:"filePath"`
+	TextRange sonarTextRange `json:"textRange"`
+}
+
+type sonarTextRange struct {
+	StartLine int `json:"startLine"`
+	// 0-based, unlike token.Position.
+	StartColumn int `json:"startColumn"`
+}
+
+// sonarSeverities maps the severities of issues to SonarQube severities.
+var sonarSeverities = map[string]string{
+	"error":   "CRITICAL",
+	"warning": "MAJOR",
+	"info":    "INFO",
+}
+
+// sonarJSON converts issues into the SonarQube generic issue import format.
+func sonarJSON(issues []nestif.Issue) ([]byte, error) {
+	r := sonarReport{Issues: make([]sonarIssue, 0, len(issues))}
+	for _, issue := range issues {
+		r.Issues = append(r.Issues, sonarIssue{
+			EngineID: "nestif",
+			RuleID:   ruleNestedIf,
+			Severity: sonarSeverities[issue.Severity],
+			Type:     "CODE_SMELL",
+			PrimaryLocation: sonarLocation{
+				Message:  issue.Message,
+				FilePath: issue.Pos.Filename,
+				TextRange: sonarTextRange{
+					StartLine:   issue.Pos.Line,
+					StartColumn: issue.Pos.Column - 1,
+				},
+			},
+		})
+	}
+	return json.Marshal(r)
+}
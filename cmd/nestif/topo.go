@@ -0,0 +1,116 @@
+// Copyright 2020 Ryo Nakao <ryo@nakao.dev>.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/nakabonne/nestif"
+)
+
+// sortTopologically reorders issues so that packages come before the packages
+// importing them. The order within a package is kept as is.
+func (a *app) sortTopologically(issues []nestif.Issue) {
+	dirOf := func(issue nestif.Issue) string {
+		dir, err := filepath.Abs(filepath.Dir(issue.Pos.Filename))
+		if err != nil {
+			return filepath.Dir(issue.Pos.Filename)
+		}
+		return dir
+	}
+	checked := make(map[string]bool)
+	var dirs []string
+	for _, issue := range issues {
+		if d := dirOf(issue); !checked[d] {
+			checked[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	sort.Strings(dirs)
+	// Packages whose files are given one by one haven't been loaded yet.
+	for _, d := range dirs {
+		if _, ok := a.pkgImports[d]; !ok {
+			if _, err := a.loadPackages(d, "."); err != nil {
+				a.debug(err)
+			}
+		}
+	}
+
+	// importedBy maps a package directory to the checked ones importing it.
+	importedBy := make(map[string][]string)
+	indegree := make(map[string]int)
+	for _, d := range dirs {
+		for _, dep := range a.checkedImports(d, checked) {
+			importedBy[dep] = append(importedBy[dep], d)
+			indegree[d]++
+		}
+	}
+
+	rank := make(map[string]int, len(dirs))
+	var queue []string
+	for _, d := range dirs {
+		if indegree[d] == 0 {
+			queue = append(queue, d)
+		}
+	}
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		rank[d] = len(rank)
+		for _, importer := range importedBy[d] {
+			indegree[importer]--
+			if indegree[importer] == 0 {
+				queue = append(queue, importer)
+			}
+		}
+	}
+	// Packages in an import cycle go last.
+	for _, d := range dirs {
+		if _, ok := rank[d]; !ok {
+			rank[d] = len(rank)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return rank[dirOf(issues[i])] < rank[dirOf(issues[j])]
+	})
+}
+
+// checkedImports gives the directories of the checked packages imported by the package in dir.
+func (a *app) checkedImports(dir string, checked map[string]bool) []string {
+	var deps []string
+	for _, id := range a.pkgImports[dir] {
+		if d, ok := a.dirByID[id]; ok && checked[d] && d != dir {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// recordImports keeps the packages imported by the loaded package, by its
+// absolute directory, to be sorted by them with --topo.
+func (a *app) recordImports(pkg *packages.Package) {
+	// The test variant is left out, whose imports aren't of the package itself.
+	if pkg.ID != pkg.PkgPath || len(pkg.GoFiles) == 0 {
+		return
+	}
+	if a.pkgImports == nil {
+		a.pkgImports = make(map[string][]string)
+		a.dirByID = make(map[string]string)
+	}
+	dir := filepath.Dir(pkg.GoFiles[0])
+	a.dirByID[pkg.ID] = dir
+	ids := []string{}
+	for _, imp := range pkg.Imports {
+		ids = append(ids, imp.ID)
+	}
+	sort.Strings(ids)
+	a.pkgImports[dir] = ids
+}
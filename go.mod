@@ -5,4 +5,6 @@ go 1.15
 require (
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.4.0
+	golang.org/x/tools v0.1.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -8,132 +8,1048 @@
 package nestif
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/parser"
 	"go/printer"
 	"go/token"
 	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // Issue represents an issue of root if statement that has nested ifs.
+// A root if is an if statement not nested in another if of the same function,
+// wherever it is otherwise, like in a loop or a switch case. Ifs in its else
+// branches are part of it, not roots of their own.
 type Issue struct {
 	Pos        token.Position
+	EndPos     token.Position
 	Complexity int
 	Message    string
+	// Condition is the printed condition of the root if.
+	Condition string
+	// Path is a breadcrumb of the constructs leading to the deepest nested if,
+	// like "if > for > if".
+	Path string
+	// RelLine is the line offset of the if from the enclosing function's opening line.
+	RelLine int
+	// FuncName is the name of the function containing the if, like "F" or "(*T).M".
+	// Function literals are named after the enclosing function, like "F.func1",
+	// and nested ones like "F.func1.1". The ones outside functions are named
+	// like "glob..func1".
+	FuncName string
+	// Severity is "info", "warning" or "error", by the Checker's complexity bands.
+	Severity string
+	// Invertible is whether a nested if ends with a return, break, continue or
+	// panic, so that its condition could be inverted into an early exit.
+	Invertible bool
+	// Breakdown is how Complexity adds up.
+	Breakdown ComplexityBreakdown
+}
+
+// ComplexityBreakdown is the complexity of an if statement by where it comes from.
+// The fields add up to the complexity.
+type ComplexityBreakdown struct {
+	// Ifs nested in the root if, and the root if itself when it's in a loop
+	// or select counted as nesting, by their nesting levels.
+	Nesting int
+	// Switch, select and loop statements counted as nesting.
+	Constructs int
+	// `else` blocks.
+	Else int
+	// `else if` branches, including the penalty for long chains.
+	ElseIf int
+	// Negated conditions, with CountNegations.
+	Negations int
+	// Sequences of boolean operators in conditions, with IncludeBooleanOps.
+	BooleanOps int
+	// Init statements, with InitClausePenalty.
+	InitClauses int
+	// Labeled statements and jumps to labels, with IncludeLabels.
+	Labels int
+}
+
+// Total returns the sum of the complexities.
+func (b ComplexityBreakdown) Total() int {
+	return b.Nesting + b.Constructs + b.Else + b.ElseIf + b.Negations + b.BooleanOps + b.InitClauses + b.Labels
+}
+
+// FuncComplexity represents the total complexity of a function that has if statements.
+type FuncComplexity struct {
+	Pos        token.Position
+	Complexity int
+	// The highest complexity among its root if statements.
+	MaxComplexity int
+	// The number of guard clauses, if statements without nesting that end with
+	// an early return, break, continue or panic.
+	Guards int
 }
 
 // Checker represents a checker that finds nested if statements.
 type Checker struct {
 	// Minimum complexity to report.
 	MinComplexity int
+	// Maximum complexity to report. 0 means no upper bound.
+	MaxComplexity int
+	// Minimum number of lines the root if must span to be reported, counted
+	// from its first line to its last one. 0 means no line-based filtering.
+	MinLines int
+	// Whether to report at the deepest nested if instead of the root if.
+	ReportAtDeepest bool
+	// Whether to increase complexity by 1 for each negated condition like
+	// `!(a == b)` or `!!x`, and suggest simplifying them.
+	CountNegations bool
+	// Whether to treat if statements with an empty body as zero complexity.
+	IgnoreEmptyBody bool
+	// Whether to count switch statements nested in an if like nested ifs.
+	IncludeSwitch bool
+	// Whether to count for and range loops as nesting. Loops enclosing the root
+	// if raise the level it starts at, and loops inside it are counted like nested
+	// ifs. `else` and `else if` still increase complexity by 1 wherever they are.
+	IncludeLoops bool
+	// Whether to count select statements as nesting, in the same way as loops.
+	IncludeSelect bool
+	// Whether ifs in a function literal continue from the nesting level around
+	// the literal, as part of the if around it. By default the literal is checked
+	// as a function of its own, named like "F.func1", and its ifs start from level 0.
+	KeepClosureNesting bool
+	// Whether to increase complexity by 1 for each sequence of like logical
+	// operators in conditions, so that `a && b && c || d` adds 2.
+	IncludeBooleanOps bool
+	// Whether to count `if err != nil` blocks. By default they are trivial
+	// error checks that contribute nothing, and the ifs in them aren't counted.
+	// Error checks with init statements nested in each other are counted
+	// regardless, so that the ladder is reported.
+	IfErr bool
+	// Whether CheckFile, CheckSource and CheckDir skip generated files.
+	SkipGenerated bool
+	// Extra complexity added for each `else if` beyond the first MaxElseIfChain
+	// in a chain, so that long ladders score higher. 0 disables it.
+	ElseIfChainPenalty int
+	MaxElseIfChain     int
+	// Complexity added for an `else` block. nil means 1, and 0 makes it free.
+	ElseWeight *int
+	// Exponent applied to the nesting level each nested if adds, so that it
+	// adds depth^DepthWeight rounded to the nearest integer. 0 or 1 keeps the
+	// nesting linear, and higher values make deeper levels disproportionately worse.
+	DepthWeight float64
+	// Whether only exported functions and methods are checked. Methods are
+	// checked by their own names, even if their receiver types are unexported.
+	// Function literals outside functions are skipped.
+	ExportedOnly bool
+	// Whether labeled statements and jumps to labels, like `goto L` or
+	// `break L`, in if statements add complexity, 1 each.
+	IncludeLabels bool
+	// Extra complexity added for each if with an init statement, like
+	// `if err := f(); err != nil`. 0 disables it.
+	InitClausePenalty int
+	// Complexities from which issues are warnings and errors. Issues below
+	// WarnComplexity are info. Either being 0 leaves that band out, so by
+	// default every issue is a warning.
+	WarnComplexity  int
+	ErrorComplexity int
+	// Builds the message of each issue from the condition of the root if, the
+	// complexity and the position reported, in place of the built-in wording
+	// and the suggestions appended to it. nil keeps the default.
+	// It's left out of JSON, so that a Checker can still be encoded.
+	MessageFunc func(cond string, complexity int, pos token.Position) string `json:"-"`
 
 	// For debug mode.
 	debugWriter io.Writer
-	issues      []Issue
+}
+
+// fileState holds the state of a single Check call. Nothing else is written
+// during a check, so that Check can be called concurrently.
+type fileState struct {
+	fset   *token.FileSet
+	issues []Issue
+	funcs  []FuncComplexity
+	// Lines having a nolint directive for nestif.
+	nolintLines map[int]bool
+	// Names of the function literals in the file.
+	closures map[*ast.FuncLit]string
 }
 
 // Check inspects a single file and returns found issues.
+// It is safe to call Check from multiple goroutines.
 func (c *Checker) Check(f *ast.File, fset *token.FileSet) []Issue {
-	c.issues = []Issue{} // refresh
-	ast.Inspect(f, func(n ast.Node) bool {
-		fn, ok := n.(*ast.FuncDecl)
-		if !ok || fn.Body == nil {
-			return true
+	issues, _ := c.CheckContext(context.Background(), f, fset)
+	return issues
+}
+
+// CheckContext is like Check, but stops inspecting the file once ctx is done.
+// The context is checked before each top-level declaration, and ctx.Err() is
+// returned along with no issues when cancelled.
+func (c *Checker) CheckContext(ctx context.Context, f *ast.File, fset *token.FileSet) ([]Issue, error) {
+	st, err := c.check(ctx, f, fset)
+	if err != nil {
+		return nil, err
+	}
+	return st.issues, nil
+}
+
+// CheckFuncs is like Check, but also returns the total complexities of the
+// functions that have at least one if statement, in the order they appear.
+func (c *Checker) CheckFuncs(f *ast.File, fset *token.FileSet) ([]Issue, []FuncComplexity) {
+	st, _ := c.check(context.Background(), f, fset)
+	return st.issues, st.funcs
+}
+
+// CheckDecl is like Check, but inspects only the given top-level declaration
+// of f, so that the functions of a file can be checked one by one.
+func (c *Checker) CheckDecl(f *ast.File, decl ast.Decl, fset *token.FileSet) []Issue {
+	st := newFileState(f, fset)
+	// The function literals outside functions before decl are counted
+	// to number the ones in it.
+	var globs int
+	for _, d := range f.Decls {
+		if d == decl {
+			break
 		}
-		for _, stmt := range fn.Body.List {
-			c.checkFunc(&stmt, fset)
+		if gd, ok := d.(*ast.GenDecl); ok && !c.ExportedOnly {
+			ast.Inspect(gd, func(n ast.Node) bool {
+				if _, ok := n.(*ast.FuncLit); ok {
+					globs++
+					return false
+				}
+				return true
+			})
 		}
-		return true
-	})
+	}
+	c.checkDecl(st, decl, &globs)
+	return st.issues
+}
+
+func newFileState(f *ast.File, fset *token.FileSet) *fileState {
+	return &fileState{
+		fset:        fset,
+		issues:      []Issue{},
+		funcs:       []FuncComplexity{},
+		nolintLines: nolintLines(f, fset),
+		closures:    make(map[*ast.FuncLit]string),
+	}
+}
+
+func (c *Checker) check(ctx context.Context, f *ast.File, fset *token.FileSet) (*fileState, error) {
+	st := newFileState(f, fset)
+	var globs int
+	for _, decl := range f.Decls {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c.checkDecl(st, decl, &globs)
+	}
+	return st, nil
+}
+
+// checkDecl checks the functions in the top-level declaration. Function literals
+// outside functions, like in package-level variables, are numbered across the file
+// by globs, and named "glob..func1" like the compiler does.
+func (c *Checker) checkDecl(st *fileState, decl ast.Decl, globs *int) {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Body == nil || c.ExportedOnly && !decl.Name.IsExported() {
+			return
+		}
+		name := funcName(decl)
+		nameClosures(st.closures, decl.Body, name, false)
+		c.checkBody(st, decl, decl.Body, name)
+	case *ast.GenDecl:
+		if c.ExportedOnly {
+			return
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			lit, ok := n.(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+			*globs++
+			name := fmt.Sprintf("glob..func%d", *globs)
+			st.closures[lit] = name
+			nameClosures(st.closures, lit.Body, name, true)
+			c.checkBody(st, lit, lit.Body, name)
+			return false
+		})
+	}
+}
+
+// Reset clears the caches kept across calls, leaving the configuration as is.
+// Checker has no such caches for now, since everything found by a check is
+// returned by it, so Reset does nothing.
+func (c *Checker) Reset() {}
+
+// CheckFiles inspects all given files sharing the same FileSet, and returns
+// found issues combined. Issues found more than once are reported only once.
+func (c *Checker) CheckFiles(files map[string]*ast.File, fset *token.FileSet) []Issue {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	issues := []Issue{}
+	seen := make(map[token.Position]bool)
+	for _, name := range names {
+		for _, issue := range c.Check(files[name], fset) {
+			if seen[issue.Pos] {
+				continue
+			}
+			seen[issue.Pos] = true
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// CheckPaths reads and parses the files at the given paths, and returns found
+// issues combined. Unlike CheckFile, a file that can't be read or parsed doesn't
+// stop the others from being checked; its error is returned keyed by its path.
+func (c *Checker) CheckPaths(paths []string) ([]Issue, map[string]error) {
+	issues := []Issue{}
+	var errs map[string]error
+	for _, path := range paths {
+		is, err := c.CheckFile(path)
+		if err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[path] = err
+			continue
+		}
+		issues = append(issues, is...)
+	}
+	return issues, errs
+}
+
+// GroupByFile groups issues by the file name of their positions, ordered by
+// line and then column within each file.
+func GroupByFile(issues []Issue) map[string][]Issue {
+	groups := make(map[string][]Issue)
+	for _, issue := range issues {
+		groups[issue.Pos.Filename] = append(groups[issue.Pos.Filename], issue)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			p, q := group[i].Pos, group[j].Pos
+			if p.Line != q.Line {
+				return p.Line < q.Line
+			}
+			return p.Column < q.Column
+		})
+	}
+	return groups
+}
+
+// CheckFile reads and parses the file at the given path, and returns found issues.
+// Each call parses with a FileSet of its own, so that the Checker holds on to
+// nothing between calls; positions in issues are resolved, and stay consistent.
+func (c *Checker) CheckFile(path string) ([]Issue, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.CheckSource(path, src)
+}
+
+// CheckSource parses the given source, and returns found issues. The filename
+// is used only for the positions of the issues, as is.
+func (c *Checker) CheckSource(filename string, src []byte) ([]Issue, error) {
+	if c.SkipGenerated && IsGenerated(src) {
+		return []Issue{}, nil
+	}
+	// Issues hold resolved positions, so the FileSet isn't needed after this call.
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return c.Check(f, fset), nil
+}
+
+// CheckDir checks the Go files of the package in the given directory, including
+// cgo and test files, and returns found issues combined. A directory without
+// Go files has no issues.
+func (c *Checker) CheckDir(dir string) ([]Issue, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		if _, nogo := err.(*build.NoGoError); nogo {
+			return []Issue{}, nil
+		}
+		return nil, err
+	}
+	var files []string
+	files = append(files, pkg.GoFiles...)
+	files = append(files, pkg.CgoFiles...)
+	files = append(files, pkg.TestGoFiles...)
 
-	return c.issues
+	issues := []Issue{}
+	for _, f := range files {
+		is, err := c.CheckFile(filepath.Join(pkg.Dir, f))
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, is...)
+	}
+	return issues, nil
 }
 
-// checkFunc inspects a function and sets a list of issues if there are.
-func (c *Checker) checkFunc(stmt *ast.Stmt, fset *token.FileSet) {
+// IsGenerated reports whether the source file is generated code
+// according the rules from https://golang.org/s/generatedcode.
+func IsGenerated(src []byte) bool {
+	var (
+		genHdr = []byte("// Code generated ")
+		genFtr = []byte(" DO NOT EDIT.")
+	)
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for sc.Scan() {
+		b := sc.Bytes()
+		if bytes.HasPrefix(b, genHdr) && bytes.HasSuffix(b, genFtr) && len(b) >= len(genHdr)+len(genFtr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBody checks the body of the given function, which is either a declaration
+// or a literal outside functions, and records its complexity if it has ifs.
+func (c *Checker) checkBody(st *fileState, fn ast.Node, body *ast.BlockStmt, name string) {
+	fc := FuncComplexity{Pos: st.fset.Position(fn.Pos())}
+	var ifs int
+	for _, stmt := range body.List {
+		ifs += c.checkFunc(st, &stmt, fn, name, &fc)
+	}
+	if ifs > 0 {
+		st.funcs = append(st.funcs, fc)
+	}
+}
+
+// checkFunc inspects a top-level statement of a function and sets a list of issues
+// if there are. It checks every root if in the statement, including the ones
+// in loops and switch cases, without descending into them, so that each if is
+// counted as part of exactly one root.
+// It adds the complexities of the root ifs to fc, and returns how many there are.
+func (c *Checker) checkFunc(st *fileState, stmt *ast.Stmt, fn ast.Node, fnName string, fc *FuncComplexity) (ifs int) {
+	// Nodes enclosing the root if.
+	var stack []ast.Node
 	ast.Inspect(*stmt, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
 		ifStmt, ok := n.(*ast.IfStmt)
 		if !ok {
+			stack = append(stack, n)
 			return true
 		}
 
-		c.checkIf(ifStmt, fset)
+		var nesting int
+		name := fnName
+		for _, node := range stack {
+			if c.IncludeLoops && isLoop(node) {
+				nesting++
+			}
+			if _, ok := node.(*ast.SelectStmt); ok && c.IncludeSelect {
+				nesting++
+			}
+			if lit, ok := node.(*ast.FuncLit); ok {
+				name = st.closures[lit]
+				// The loops and selects around the literal don't count in it.
+				if !c.KeepClosureNesting {
+					nesting = 0
+				}
+			}
+		}
+		complexity, closures := c.checkIf(st, ifStmt, fn, name, nesting)
+		fc.Complexity += complexity
+		if complexity > fc.MaxComplexity {
+			fc.MaxComplexity = complexity
+		}
+		if isGuard(ifStmt) {
+			fc.Guards++
+		}
+		ifs++
+		for _, lit := range closures {
+			for _, s := range lit.Body.List {
+				ifs += c.checkFunc(st, &s, fn, st.closures[lit], fc)
+			}
+		}
 		return false
 	})
+	return
 }
 
 // checkIf inspects a if statement and sets an issue if there is.
-func (c *Checker) checkIf(stmt *ast.IfStmt, fset *token.FileSet) {
+// The given name is of the function containing it, and nesting is the level
+// the if statement starts at. It returns the complexity of the if statement,
+// and the function literals in it, unless their nesting is kept, which are
+// left to be checked as functions of their own.
+func (c *Checker) checkIf(st *fileState, stmt *ast.IfStmt, fn ast.Node, name string, nesting int) (int, []*ast.FuncLit) {
+	fset := st.fset
 	v := newVisitor()
+	v.nesting = nesting
+	v.countNegations = c.CountNegations
+	v.ignoreEmptyBody = c.IgnoreEmptyBody
+	v.includeSwitch = c.IncludeSwitch
+	v.includeLoops = c.IncludeLoops
+	v.includeSelect = c.IncludeSelect
+	v.keepClosures = c.KeepClosureNesting
+	v.booleanOps = c.IncludeBooleanOps
+	v.ifErr = c.IfErr
+	v.elseIfPenalty = c.ElseIfChainPenalty
+	v.maxElseIfChain = c.MaxElseIfChain
+	v.initPenalty = c.InitClausePenalty
+	v.includeLabels = c.IncludeLabels
+	v.depthWeight = c.DepthWeight
+	v.elseWeight = 1
+	if c.ElseWeight != nil {
+		v.elseWeight = *c.ElseWeight
+	}
 	ast.Walk(v, stmt)
-	if v.complexity < c.MinComplexity {
-		return
+	complexity := v.breakdown.Total()
+	if line := fset.Position(stmt.Pos()).Line; st.nolintLines[line] || st.nolintLines[line-1] {
+		return complexity, v.closures
+	}
+	if complexity < c.MinComplexity {
+		return complexity, v.closures
+	}
+	if c.MaxComplexity > 0 && complexity > c.MaxComplexity {
+		return complexity, v.closures
+	}
+	if span := fset.Position(stmt.End()).Line - fset.Position(stmt.Pos()).Line; span < c.MinLines {
+		return complexity, v.closures
 	}
 	pos := fset.Position(stmt.Pos())
-	c.issues = append(c.issues, Issue{
+	// A root if skipped as a trivial error check has no deepest if.
+	if c.ReportAtDeepest && v.deepestIf != nil {
+		pos = fset.Position(v.deepestIf.Pos())
+	}
+	cond := c.exprString(stmt.Cond, fset)
+	msg := c.makeMessage(complexity, cond)
+	if len(v.negations) > 0 {
+		conds := make([]string, 0, len(v.negations))
+		for _, n := range v.negations {
+			conds = append(conds, "`"+c.exprString(n, fset)+"`")
+		}
+		msg = fmt.Sprintf("%s; consider simplifying negated conditions: %s", msg, strings.Join(conds, ", "))
+	}
+	if v.errLadder {
+		msg += "; consider flattening the nested error checks into sequential statements with early returns"
+	} else if v.invertible {
+		msg += "; consider inverting the nested condition into an early return"
+	}
+	if c.MessageFunc != nil {
+		msg = c.MessageFunc(cond, complexity, pos)
+	}
+	st.issues = append(st.issues, Issue{
 		Pos:        pos,
-		Complexity: v.complexity,
-		Message:    c.makeMessage(v.complexity, stmt.Cond, fset),
+		EndPos:     fset.Position(stmt.End()),
+		Complexity: complexity,
+		Message:    msg,
+		Condition:  cond,
+		Path:       v.deepestPath,
+		RelLine:    pos.Line - fset.Position(fn.Pos()).Line,
+		FuncName:   name,
+		Severity:   c.severity(complexity),
+		Invertible: v.invertible,
+		Breakdown:  v.breakdown,
+	})
+	return complexity, v.closures
+}
+
+// funcName returns the name of the given function, qualified by the receiver
+// type for methods, like "(*T).M" or "T.M".
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	var star bool
+	if s, ok := typ.(*ast.StarExpr); ok {
+		typ, star = s.X, true
+	}
+	if idx, ok := typ.(*ast.IndexExpr); ok {
+		typ = idx.X
+	}
+	recv := "?"
+	if id, ok := typ.(*ast.Ident); ok {
+		recv = id.Name
+	}
+	if star {
+		return "(*" + recv + ")." + fn.Name.Name
+	}
+	return recv + "." + fn.Name.Name
+}
+
+// nameClosures names the function literals in body after the enclosing function,
+// numbering them in source order like the compiler does: "F.func1" for the ones
+// directly in F, and "F.func1.1" for the ones nested in those.
+func nameClosures(names map[*ast.FuncLit]string, body ast.Node, prefix string, nested bool) {
+	var n int
+	ast.Inspect(body, func(node ast.Node) bool {
+		lit, ok := node.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		n++
+		name := fmt.Sprintf("%s.func%d", prefix, n)
+		if nested {
+			name = fmt.Sprintf("%s.%d", prefix, n)
+		}
+		names[lit] = name
+		nameClosures(names, lit.Body, name, true)
+		return false
 	})
 }
 
 type visitor struct {
-	complexity int
-	nesting    int
+	breakdown ComplexityBreakdown
+	nesting   int
 	// To avoid adding complexity including nesting level to `else if`.
-	elseifs map[*ast.IfStmt]bool
+	// The value is the position of the `else if` in its chain, starting from 1.
+	elseifs map[*ast.IfStmt]int
+	// Labels of the constructs currently being traversed. Empty for
+	// nodes that don't appear in the breadcrumb.
+	path        []string
+	deepestPath string
+	deepestLen  int
+	deepestIf   *ast.IfStmt
+
+	countNegations  bool
+	negations       []ast.Expr
+	ignoreEmptyBody bool
+	includeSwitch   bool
+	includeLoops    bool
+	includeSelect   bool
+	keepClosures    bool
+	booleanOps      bool
+	ifErr           bool
+	elseIfPenalty   int
+	maxElseIfChain  int
+	elseWeight      int
+	depthWeight     float64
+	initPenalty     int
+	includeLabels   bool
+	// Whether error checks with init statements are nested directly in each other.
+	errLadder bool
+	// Whether a nested if ends by leaving early.
+	invertible bool
+	// Function literals in the if, which aren't walked unless keepClosures.
+	closures []*ast.FuncLit
 }
 
 func newVisitor() *visitor {
 	return &visitor{
-		elseifs: make(map[*ast.IfStmt]bool),
+		elseifs: make(map[*ast.IfStmt]int),
 	}
 }
 
 // Visit traverses an AST in depth-first order by calling itself
 // recursively, and calculates the complexities of if statements.
 func (v *visitor) Visit(n ast.Node) ast.Visitor {
-	ifStmt, ok := n.(*ast.IfStmt)
-	if !ok {
-		return v
+	switch t := n.(type) {
+	case nil:
+		v.path = v.path[:len(v.path)-1]
+		return nil
+	case *ast.IfStmt:
+		return v.visitIf(t)
+	case *ast.LabeledStmt:
+		if v.includeLabels {
+			v.breakdown.Labels++
+		}
+	case *ast.BranchStmt:
+		if v.includeLabels && t.Label != nil {
+			v.breakdown.Labels++
+		}
+	case *ast.SwitchStmt:
+		if v.includeSwitch {
+			return v.visitNested(t.Body, "switch")
+		}
+	case *ast.TypeSwitchStmt:
+		if v.includeSwitch {
+			return v.visitNested(t.Body, "switch")
+		}
+	case *ast.ForStmt:
+		if v.includeLoops {
+			return v.visitNested(t.Body, "for")
+		}
+	case *ast.RangeStmt:
+		if v.includeLoops {
+			return v.visitNested(t.Body, "for")
+		}
+	case *ast.SelectStmt:
+		if v.includeSelect {
+			return v.visitNested(t.Body, "select")
+		}
+	case *ast.FuncLit:
+		if !v.keepClosures {
+			v.closures = append(v.closures, t)
+			return nil
+		}
+	}
+	v.path = append(v.path, constructLabel(n))
+	return v
+}
+
+// visitNested walks the body of a construct other than if, which is
+// as hard to read as a nested if, while increasing the nesting level.
+func (v *visitor) visitNested(body *ast.BlockStmt, label string) ast.Visitor {
+	v.breakdown.Constructs += v.nesting
+	v.path = append(v.path, label)
+	v.nesting++
+	ast.Walk(v, body)
+	v.nesting--
+	v.path = v.path[:len(v.path)-1]
+	return nil
+}
+
+func (v *visitor) visitIf(ifStmt *ast.IfStmt) ast.Visitor {
+	// Error checks nested in each other aren't trivial, so the ladder is
+	// counted even if error checks aren't.
+	if isErrCheck(ifStmt) {
+		for _, stmt := range ifStmt.Body.List {
+			if nested, ok := stmt.(*ast.IfStmt); ok && isErrCheck(nested) {
+				v.errLadder = true
+			}
+		}
+	}
+	if !v.ifErr && !v.errLadder && isErrNotNil(ifStmt.Cond) {
+		return nil
+	}
+	// `else if` is a part of the chain, so it doesn't go deeper.
+	elseif := v.elseifs[ifStmt] > 0
+	if !elseif {
+		v.path = append(v.path, "if")
+	}
+	v.recordPath(ifStmt)
+	// The root if is the only one in the path at this point.
+	if len(v.path) > 1 && endsEarly(ifStmt.Body) {
+		v.invertible = true
 	}
 
-	v.incComplexity(ifStmt)
+	// Placeholders like `if cond {}` don't count at all if configured.
+	if !v.ignoreEmptyBody || len(ifStmt.Body.List) > 0 {
+		v.incComplexity(ifStmt)
+		if v.countNegations && isNegated(ifStmt.Cond) {
+			v.breakdown.Negations++
+			v.negations = append(v.negations, ifStmt.Cond)
+		}
+		if v.booleanOps {
+			v.breakdown.BooleanOps += booleanOpSequences(ifStmt.Cond)
+		}
+		if ifStmt.Init != nil {
+			v.breakdown.InitClauses += v.initPenalty
+		}
+	}
+	// The init statement is never walked so that it doesn't contribute,
+	// however compound it is, apart from the flat penalty above; only
+	// the body and else are counted.
 	v.nesting++
 	ast.Walk(v, ifStmt.Body)
 	v.nesting--
 
 	switch t := ifStmt.Else.(type) {
 	case *ast.BlockStmt:
-		v.complexity++
+		v.breakdown.Else += v.elseWeight
 		v.nesting++
 		ast.Walk(v, t)
 		v.nesting--
 	case *ast.IfStmt:
-		v.elseifs[t] = true
+		v.elseifs[t] = v.elseifs[ifStmt] + 1
 		ast.Walk(v, t)
 	}
 
+	if !elseif {
+		v.path = v.path[:len(v.path)-1]
+	}
 	return nil
 }
 
+// recordPath keeps the breadcrumb if the current if is the deepest one so far.
+func (v *visitor) recordPath(ifStmt *ast.IfStmt) {
+	labels := make([]string, 0, len(v.path))
+	for _, l := range v.path {
+		if l != "" {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) <= v.deepestLen {
+		return
+	}
+	v.deepestLen = len(labels)
+	v.deepestIf = ifStmt
+	v.deepestPath = strings.Join(labels, " > ")
+}
+
+// constructLabel gives the name to be shown in the breadcrumb.
+func constructLabel(n ast.Node) string {
+	switch n.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		return "for"
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return "switch"
+	case *ast.SelectStmt:
+		return "select"
+	case *ast.FuncLit:
+		return "func"
+	}
+	return ""
+}
+
 func (v *visitor) incComplexity(n *ast.IfStmt) {
-	// In case of `else if`, increase by 1.
-	if v.elseifs[n] {
-		v.complexity++
+	// In case of `else if`, increase by 1, plus the penalty for long chains.
+	if pos := v.elseifs[n]; pos > 0 {
+		v.breakdown.ElseIf++
+		if pos > v.maxElseIfChain {
+			v.breakdown.ElseIf += v.elseIfPenalty
+		}
 	} else {
-		v.complexity += v.nesting
+		v.breakdown.Nesting += v.weightDepth(v.nesting)
+	}
+}
+
+// weightDepth returns the complexity the given nesting level adds, raised
+// to the power of depthWeight unless it is 0 or 1.
+func (v *visitor) weightDepth(nesting int) int {
+	if v.depthWeight == 0 || v.depthWeight == 1 {
+		return nesting
+	}
+	return int(math.Round(math.Pow(float64(nesting), v.depthWeight)))
+}
+
+func isLoop(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		return true
+	}
+	return false
+}
+
+// isGuard reports whether the if statement has no else, leaves early, and has
+// no blocks nested in it. It's decided by the structure alone, regardless of
+// the options adding to the complexity.
+func isGuard(stmt *ast.IfStmt) bool {
+	if stmt.Else != nil || !endsEarly(stmt.Body) {
+		return false
+	}
+	nested := false
+	ast.Inspect(stmt.Body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			nested = true
+		case *ast.FuncLit:
+			return false
+		}
+		return !nested
+	})
+	return !nested
+}
+
+// endsEarly reports whether the block ends with a return, break, continue or panic.
+func endsEarly(body *ast.BlockStmt) bool {
+	if len(body.List) == 0 {
+		return false
+	}
+	switch last := body.List[len(body.List)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		call, ok := last.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		id, ok := call.Fun.(*ast.Ident)
+		return ok && id.Name == "panic"
+	}
+	return false
+}
+
+// isErrNotNil reports whether the condition is `err != nil`. A variable named
+// err declared with a type other than error, like `var err bool`, doesn't count.
+func isErrNotNil(cond ast.Expr) bool {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return false
+	}
+	x, ok := bin.X.(*ast.Ident)
+	if !ok || x.Name != "err" {
+		return false
+	}
+	if y, ok := bin.Y.(*ast.Ident); !ok || y.Name != "nil" {
+		return false
+	}
+	return isErrorTyped(x)
+}
+
+// isErrorTyped reports whether the identifier may be of the error type, judging
+// from its declaration. Ones declared without an explicit type, like `err := f()`,
+// are assumed to be errors.
+func isErrorTyped(id *ast.Ident) bool {
+	if id.Obj == nil {
+		return true
 	}
+	var typ ast.Expr
+	switch decl := id.Obj.Decl.(type) {
+	case *ast.ValueSpec:
+		typ = decl.Type
+	case *ast.Field:
+		typ = decl.Type
+	}
+	if typ == nil {
+		return true
+	}
+	t, ok := typ.(*ast.Ident)
+	return ok && t.Name == "error"
+}
+
+// isErrCheck reports whether the if statement is like `if err := f(); err != nil`.
+func isErrCheck(stmt *ast.IfStmt) bool {
+	assign, ok := stmt.Init.(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	cond, ok := stmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	x, ok := cond.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	if y, ok := cond.Y.(*ast.Ident); !ok || y.Name != "nil" {
+		return false
+	}
+	for _, l := range assign.Lhs {
+		if id, ok := l.(*ast.Ident); ok && id.Name == x.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// nolintLines returns the lines having a `//nolint` or `//nolint:nestif` directive.
+func nolintLines(f *ast.File, fset *token.FileSet) map[int]bool {
+	lines := make(map[int]bool)
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if isNolint(c.Text) {
+				lines[fset.Position(c.Pos()).Line] = true
+			}
+		}
+	}
+	return lines
+}
+
+// isNolint reports whether the comment is a nolint directive applied to nestif.
+func isNolint(text string) bool {
+	if !strings.HasPrefix(text, "//nolint") {
+		return false
+	}
+	rest := text[len("//nolint"):]
+	if rest == "" || strings.HasPrefix(rest, " ") {
+		return true
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return false
+	}
+	linters := strings.Fields(rest[1:])
+	if len(linters) == 0 {
+		return false
+	}
+	for _, l := range strings.Split(linters[0], ",") {
+		if l == "nestif" {
+			return true
+		}
+	}
+	return false
+}
+
+// booleanOpSequences returns the number of sequences of like logical operators
+// in cond, in the way cognitive complexity counts them: `a && b && c` has 1,
+// and `a && b || c && d` has 3. Parentheses don't break a sequence.
+func booleanOpSequences(cond ast.Expr) int {
+	var ops []token.Token
+	var collect func(e ast.Expr)
+	collect = func(e ast.Expr) {
+		switch e := e.(type) {
+		case *ast.ParenExpr:
+			collect(e.X)
+		case *ast.BinaryExpr:
+			collect(e.X)
+			if e.Op == token.LAND || e.Op == token.LOR {
+				ops = append(ops, e.Op)
+			}
+			collect(e.Y)
+		}
+	}
+	collect(cond)
+
+	var n int
+	for i, op := range ops {
+		if i == 0 || op != ops[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// isNegated reports whether the condition is a negated comparison like `!(a == b)`
+// or a double negation like `!!x`.
+func isNegated(cond ast.Expr) bool {
+	u, ok := cond.(*ast.UnaryExpr)
+	if !ok || u.Op != token.NOT {
+		return false
+	}
+	x := u.X
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		x = p.X
+	}
+	switch x := x.(type) {
+	case *ast.UnaryExpr:
+		return x.Op == token.NOT
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return true
+		}
+	}
+	return false
+}
+
+// severity gives the band the complexity falls into.
+func (c *Checker) severity(complexity int) string {
+	switch {
+	case c.ErrorComplexity > 0 && complexity >= c.ErrorComplexity:
+		return "error"
+	case c.WarnComplexity > 0 && complexity < c.WarnComplexity:
+		return "info"
+	default:
+		return "warning"
+	}
+}
+
+func (c *Checker) makeMessage(complexity int, cond string) string {
+	return fmt.Sprintf("`if %s` has complex nested blocks (complexity: %d)", cond, complexity)
 }
 
-func (c *Checker) makeMessage(complexity int, cond ast.Expr, fset *token.FileSet) string {
+func (c *Checker) exprString(expr ast.Expr, fset *token.FileSet) string {
 	p := &printer.Config{}
 	b := new(bytes.Buffer)
-	if err := p.Fprint(b, fset, cond); err != nil {
+	if err := p.Fprint(b, fset, expr); err != nil {
 		c.debug("failed to convert condition into string: %v", err)
 	}
-	return fmt.Sprintf("`if %s` has complex nested blocks (complexity: %d)", b.String(), complexity)
+	return b.String()
 }
 
 // DebugMode makes it possible to emit debug logs.
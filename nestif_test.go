@@ -8,9 +8,13 @@ package nestif
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,10 +22,22 @@ import (
 
 func TestCheck(t *testing.T) {
 	cases := []struct {
-		name          string
-		filepath      string
-		minComplexity int
-		want          []Issue
+		name           string
+		filepath       string
+		minComplexity  int
+		maxComplexity  int
+		reportDeepest  bool
+		countNegations bool
+		ignoreEmpty    bool
+		includeSwitch  bool
+		includeLoops   bool
+		includeSelect  bool
+		keepClosures   bool
+		booleanOps     bool
+		ifErr          bool
+		elseIfPenalty  int
+		maxElseIfChain int
+		want           []Issue
 	}{
 		{
 			name:          "increment for breaks in the linear flow",
@@ -35,8 +51,20 @@ func TestCheck(t *testing.T) {
 						Line:     9,
 						Column:   2,
 					},
+					EndPos: token.Position{
+						Filename: "./testdata/a.go",
+						Offset:   125,
+						Line:     12,
+						Column:   3,
+					},
 					Complexity: 1,
 					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
 				},
 			},
 		},
@@ -52,8 +80,20 @@ func TestCheck(t *testing.T) {
 						Line:     5,
 						Column:   2,
 					},
+					EndPos: token.Position{
+						Filename: "./testdata/b.go",
+						Offset:   191,
+						Line:     17,
+						Column:   3,
+					},
 					Complexity: 9,
 					Message:    "`if b1` has complex nested blocks (complexity: 9)",
+					Condition:  "b1",
+					Path:       "if > if > if > if",
+					RelLine:    2,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 9},
 				},
 			},
 		},
@@ -69,8 +109,20 @@ func TestCheck(t *testing.T) {
 						Line:     6,
 						Column:   2,
 					},
+					EndPos: token.Position{
+						Filename: "./testdata/c.go",
+						Offset:   142,
+						Line:     12,
+						Column:   3,
+					},
 					Complexity: 4,
 					Message:    "`if b1` has complex nested blocks (complexity: 4)",
+					Condition:  "b1",
+					Path:       "if > if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 3, Else: 1},
 				},
 				{
 					Pos: token.Position{
@@ -79,32 +131,1979 @@ func TestCheck(t *testing.T) {
 						Line:     14,
 						Column:   2,
 					},
+					EndPos: token.Position{
+						Filename: "./testdata/c.go",
+						Offset:   237,
+						Line:     20,
+						Column:   3,
+					},
 					Complexity: 4,
 					Message:    "`if b1` has complex nested blocks (complexity: 4)",
+					Condition:  "b1",
+					Path:       "if > if > if",
+					RelLine:    11,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 3, ElseIf: 1},
 				},
 			},
 		},
 		{
-			name:          "complexity is less than given num",
-			filepath:      "./testdata/a.go",
-			minComplexity: 2,
-			want:          []Issue{},
+			name:          "breadcrumb of the deepest nested if",
+			filepath:      "./testdata/f.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/f.go",
+						Offset:   61,
+						Line:     7,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/f.go",
+						Offset:   130,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > for > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			checker := &Checker{
-				MinComplexity: tc.minComplexity,
-			}
-			src, _ := ioutil.ReadFile(tc.filepath)
-			fset := token.NewFileSet()
-			f, _ := parser.ParseFile(fset, tc.filepath, src, parser.ParseComments)
-			i := checker.Check(f, fset)
-
-			assert.ElementsMatch(t, tc.want, i)
-		})
-	}
+		{
+			name:           "negated conditions counted",
+			filepath:       "./testdata/g.go",
+			minComplexity:  1,
+			countNegations: true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/g.go",
+						Offset:   57,
+						Line:     7,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/g.go",
+						Offset:   153,
+						Line:     10,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if !(a == b)` has complex nested blocks (complexity: 3); consider simplifying negated conditions: `!(a == b)`, `!!x`",
+					Condition:  "!(a == b)",
+					Path:       "if > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1, Negations: 2},
+				},
+			},
+		},
+		{
+			name:          "negated conditions not counted by default",
+			filepath:      "./testdata/g.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/g.go",
+						Offset:   57,
+						Line:     7,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/g.go",
+						Offset:   153,
+						Line:     10,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if !(a == b)` has complex nested blocks (complexity: 1)",
+					Condition:  "!(a == b)",
+					Path:       "if > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "init statement doesn't contribute",
+			filepath:      "./testdata/h.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/h.go",
+						Offset:   48,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/h.go",
+						Offset:   191,
+						Line:     15,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if f()` has complex nested blocks (complexity: 1)",
+					Condition:  "f()",
+					Path:       "if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "empty bodies counted by default",
+			filepath:      "./testdata/i.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/i.go",
+						Offset:   56,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/i.go",
+						Offset:   190,
+						Line:     13,
+						Column:   3,
+					},
+					Complexity: 4,
+					Message:    "`if b1` has complex nested blocks (complexity: 4)",
+					Condition:  "b1",
+					Path:       "if > if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 4},
+				},
+			},
+		},
+		{
+			name:          "empty bodies ignored",
+			filepath:      "./testdata/i.go",
+			minComplexity: 1,
+			ignoreEmpty:   true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/i.go",
+						Offset:   56,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/i.go",
+						Offset:   190,
+						Line:     13,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "method values and function values",
+			filepath:      "./testdata/j.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/j.go",
+						Offset:   72,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/j.go",
+						Offset:   122,
+						Line:     9,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if t.b` has complex nested blocks (complexity: 1)",
+					Condition:  "t.b",
+					Path:       "if > if",
+					RelLine:    1,
+					FuncName:   "(*T).Method",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/j.go",
+						Offset:   242,
+						Line:     19,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/j.go",
+						Offset:   321,
+						Line:     22,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if g()` has complex nested blocks (complexity: 1)",
+					Condition:  "g()",
+					Path:       "if > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "switch statements not counted by default",
+			filepath:      "./testdata/l.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   78,
+						Line:     8,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   178,
+						Line:     14,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > switch > if",
+					RelLine:    5,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "switch statements counted",
+			filepath:      "./testdata/l.go",
+			minComplexity: 1,
+			includeSwitch: true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   78,
+						Line:     8,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   178,
+						Line:     14,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > switch > if",
+					RelLine:    5,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 2, Constructs: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   181,
+						Line:     16,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/l.go",
+						Offset:   268,
+						Line:     20,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if",
+					RelLine:    13,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Constructs: 1},
+				},
+			},
+		},
+		{
+			name:          "complexity is greater than given max",
+			filepath:      "./testdata/d.go",
+			minComplexity: 1,
+			maxComplexity: 2,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/d.go",
+						Offset:   52,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/d.go",
+						Offset:   99,
+						Line:     9,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/d.go",
+						Offset:   102,
+						Line:     11,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/d.go",
+						Offset:   149,
+						Line:     14,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    8,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "root ifs in loops and switch cases",
+			filepath:      "./testdata/s.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   77,
+						Line:     8,
+						Column:   3,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   127,
+						Line:     11,
+						Column:   4,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    5,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   130,
+						Line:     12,
+						Column:   3,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   180,
+						Line:     15,
+						Column:   4,
+					},
+					Complexity: 1,
+					Message:    "`if b2` has complex nested blocks (complexity: 1)",
+					Condition:  "b2",
+					Path:       "if > if",
+					RelLine:    9,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   207,
+						Line:     20,
+						Column:   3,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   257,
+						Line:     23,
+						Column:   4,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    17,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   279,
+						Line:     26,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/s.go",
+						Offset:   332,
+						Line:     29,
+						Column:   5,
+					},
+					Complexity: 1,
+					Message:    "`if b2` has complex nested blocks (complexity: 1)",
+					Condition:  "b2",
+					Path:       "if > if",
+					RelLine:    23,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "report at the deepest if",
+			filepath:      "./testdata/b.go",
+			minComplexity: 1,
+			reportDeepest: true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/b.go",
+						Offset:   160,
+						Line:     13,
+						Column:   5,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/b.go",
+						Offset:   191,
+						Line:     17,
+						Column:   3,
+					},
+					Complexity: 9,
+					Message:    "`if b1` has complex nested blocks (complexity: 9)",
+					Condition:  "b1",
+					Path:       "if > if > if > if",
+					RelLine:    10,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 9},
+				},
+			},
+		},
+		{
+			name:          "nolint directives",
+			filepath:      "./testdata/n.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/n.go",
+						Offset:   194,
+						Line:     22,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/n.go",
+						Offset:   251,
+						Line:     25,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    19,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "trivial error checks ignored",
+			filepath:      "./testdata/v.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   161,
+						Line:     13,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   216,
+						Line:     16,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err == nil` has complex nested blocks (complexity: 1)",
+					Condition:  "err == nil",
+					Path:       "if > if",
+					RelLine:    10,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   260,
+						Line:     23,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   338,
+						Line:     26,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err != nil` has complex nested blocks (complexity: 1)",
+					Condition:  "err != nil",
+					Path:       "if > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "trivial error checks counted",
+			filepath:      "./testdata/v.go",
+			minComplexity: 1,
+			ifErr:         true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   76,
+						Line:     8,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   158,
+						Line:     11,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err != nil` has complex nested blocks (complexity: 1)",
+					Condition:  "err != nil",
+					Path:       "if > if",
+					RelLine:    5,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   161,
+						Line:     13,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   216,
+						Line:     16,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err == nil` has complex nested blocks (complexity: 1)",
+					Condition:  "err == nil",
+					Path:       "if > if",
+					RelLine:    10,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   260,
+						Line:     23,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/v.go",
+						Offset:   338,
+						Line:     26,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err != nil` has complex nested blocks (complexity: 1)",
+					Condition:  "err != nil",
+					Path:       "if > if",
+					RelLine:    4,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "error-wrapping ladder",
+			filepath:      "./testdata/o.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/o.go",
+						Offset:   54,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/o.go",
+						Offset:   151,
+						Line:     10,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if err != nil` has complex nested blocks (complexity: 1); consider flattening the nested error checks into sequential statements with early returns",
+					Condition:  "err != nil",
+					Path:       "if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Invertible: true,
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "loops not counted by default",
+			filepath:      "./testdata/q.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   94,
+						Line:     9,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   189,
+						Line:     13,
+						Column:   5,
+					},
+					Complexity: 2,
+					Message:    "`if b1` has complex nested blocks (complexity: 2)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1, Else: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   199,
+						Line:     17,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   305,
+						Line:     22,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > for > if",
+					RelLine:    14,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "closure starts a new scope",
+			filepath:      "./testdata/t.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/t.go",
+						Offset:   129,
+						Line:     8,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/t.go",
+						Offset:   205,
+						Line:     11,
+						Column:   5,
+					},
+					Complexity: 1,
+					Message:    "`if b2` has complex nested blocks (complexity: 1)",
+					Condition:  "b2",
+					Path:       "if > if",
+					RelLine:    5,
+					FuncName:   "_.func1",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "closure keeps the nesting",
+			filepath:      "./testdata/t.go",
+			minComplexity: 1,
+			keepClosures:  true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/t.go",
+						Offset:   48,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/t.go",
+						Offset:   212,
+						Line:     13,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > func > if > if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 3},
+				},
+			},
+		},
+		{
+			name:          "boolean operators not counted",
+			filepath:      "./testdata/u.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   123,
+						Line:     9,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   222,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if a` has complex nested blocks (complexity: 1)",
+					Condition:  "a",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "boolean operators counted",
+			filepath:      "./testdata/u.go",
+			minComplexity: 1,
+			booleanOps:    true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   52,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   120,
+						Line:     7,
+						Column:   3,
+					},
+					Complexity: 2,
+					Message:    "`if a && b && c || d` has complex nested blocks (complexity: 2)",
+					Condition:  "a && b && c || d",
+					Path:       "if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{BooleanOps: 2},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   123,
+						Line:     9,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/u.go",
+						Offset:   222,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 4,
+					Message:    "`if a` has complex nested blocks (complexity: 4)",
+					Condition:  "a",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1, BooleanOps: 3},
+				},
+			},
+		},
+		{
+			name:          "select not counted",
+			filepath:      "./testdata/w.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   98,
+						Line:     10,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   200,
+						Line:     13,
+						Column:   5,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    7,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   210,
+						Line:     17,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   329,
+						Line:     23,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > select > if",
+					RelLine:    14,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:          "select counted",
+			filepath:      "./testdata/w.go",
+			minComplexity: 1,
+			includeSelect: true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   98,
+						Line:     10,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   200,
+						Line:     13,
+						Column:   5,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    7,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 3},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   210,
+						Line:     17,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   329,
+						Line:     23,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > select > if",
+					RelLine:    14,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 2, Constructs: 1},
+				},
+			},
+		},
+		{
+			name:          "select and loops counted",
+			filepath:      "./testdata/w.go",
+			minComplexity: 1,
+			includeSelect: true,
+			includeLoops:  true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   98,
+						Line:     10,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   200,
+						Line:     13,
+						Column:   5,
+					},
+					Complexity: 5,
+					Message:    "`if b1` has complex nested blocks (complexity: 5)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    7,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 5},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   210,
+						Line:     17,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/w.go",
+						Offset:   329,
+						Line:     23,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > select > if",
+					RelLine:    14,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 2, Constructs: 1},
+				},
+			},
+		},
+		{
+			name:          "loops counted",
+			filepath:      "./testdata/q.go",
+			minComplexity: 1,
+			includeLoops:  true,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   94,
+						Line:     9,
+						Column:   4,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   189,
+						Line:     13,
+						Column:   5,
+					},
+					Complexity: 6,
+					Message:    "`if b1` has complex nested blocks (complexity: 6)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 5, Else: 1},
+				},
+				{
+					Pos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   199,
+						Line:     17,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/q.go",
+						Offset:   305,
+						Line:     22,
+						Column:   3,
+					},
+					Complexity: 3,
+					Message:    "`if b1` has complex nested blocks (complexity: 3)",
+					Condition:  "b1",
+					Path:       "if > for > if",
+					RelLine:    14,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 2, Constructs: 1},
+				},
+			},
+		},
+		{
+			name:          "else if chain without penalty",
+			filepath:      "./testdata/x.go",
+			minComplexity: 1,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/x.go",
+						Offset:   60,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/x.go",
+						Offset:   243,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 5,
+					Message:    "`if b1` has complex nested blocks (complexity: 5)",
+					Condition:  "b1",
+					Path:       "if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Else: 1, ElseIf: 4},
+				},
+			},
+		},
+		{
+			name:           "else if chain beyond the limit",
+			filepath:       "./testdata/x.go",
+			minComplexity:  1,
+			elseIfPenalty:  1,
+			maxElseIfChain: 2,
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/x.go",
+						Offset:   60,
+						Line:     6,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/x.go",
+						Offset:   243,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 7,
+					Message:    "`if b1` has complex nested blocks (complexity: 7)",
+					Condition:  "b1",
+					Path:       "if",
+					RelLine:    3,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Else: 1, ElseIf: 6},
+				},
+			},
+		},
+		{
+			name:          "complexity is less than given num",
+			filepath:      "./testdata/a.go",
+			minComplexity: 2,
+			want:          []Issue{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity:      tc.minComplexity,
+				MaxComplexity:      tc.maxComplexity,
+				ReportAtDeepest:    tc.reportDeepest,
+				CountNegations:     tc.countNegations,
+				IgnoreEmptyBody:    tc.ignoreEmpty,
+				IncludeSwitch:      tc.includeSwitch,
+				IncludeLoops:       tc.includeLoops,
+				IncludeSelect:      tc.includeSelect,
+				KeepClosureNesting: tc.keepClosures,
+				IncludeBooleanOps:  tc.booleanOps,
+				IfErr:              tc.ifErr,
+				ElseIfChainPenalty: tc.elseIfPenalty,
+				MaxElseIfChain:     tc.maxElseIfChain,
+			}
+			src, _ := ioutil.ReadFile(tc.filepath)
+			fset := token.NewFileSet()
+			f, _ := parser.ParseFile(fset, tc.filepath, src, parser.ParseComments)
+			i := checker.Check(f, fset)
+
+			assert.ElementsMatch(t, tc.want, i)
+		})
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		filepath string
+		want     []Issue
+		wantErr  bool
+	}{
+		{
+			name:     "valid file",
+			filepath: "./testdata/a.go",
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "./testdata/a.go",
+						Offset:   78,
+						Line:     9,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "./testdata/a.go",
+						Offset:   125,
+						Line:     12,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if b1` has complex nested blocks (complexity: 1)",
+					Condition:  "b1",
+					Path:       "if > if",
+					RelLine:    6,
+					FuncName:   "_",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:     "file not found",
+			filepath: "./testdata/not-found.go",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid syntax",
+			filepath: "./testdata/invalid.go",
+			wantErr:  true,
+		},
+	}
+
+	// Use the same checker to ensure that positions are consistent across calls.
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			i, err := checker.CheckFile(tc.filepath)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, i)
+		})
+	}
+}
+
+func TestReportAtDeepestErrCheck(t *testing.T) {
+	src := "package main\n\nfunc f(err error) error {\n\tif err != nil {\n\t\tif true {\n\t\t\treturn err\n\t\t}\n\t}\n\treturn nil\n}\n"
+	checker := &Checker{
+		ReportAtDeepest: true,
+	}
+	issues, err := checker.CheckSource("virtual.go", []byte(src))
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, 4, issues[0].Pos.Line)
+	assert.Equal(t, 0, issues[0].Complexity)
+}
+
+func TestCheckSource(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+		src      string
+		want     []Issue
+		wantErr  bool
+	}{
+		{
+			name:     "valid source",
+			filename: "virtual.go",
+			src:      "package main\n\nfunc main() {\n\tif a {\n\t\tif b {\n\t\t}\n\t}\n}\n",
+			want: []Issue{
+				{
+					Pos: token.Position{
+						Filename: "virtual.go",
+						Offset:   29,
+						Line:     4,
+						Column:   2,
+					},
+					EndPos: token.Position{
+						Filename: "virtual.go",
+						Offset:   51,
+						Line:     7,
+						Column:   3,
+					},
+					Complexity: 1,
+					Message:    "`if a` has complex nested blocks (complexity: 1)",
+					Condition:  "a",
+					Path:       "if > if",
+					RelLine:    1,
+					FuncName:   "main",
+					Severity:   "warning",
+					Breakdown:  ComplexityBreakdown{Nesting: 1},
+				},
+			},
+		},
+		{
+			name:     "invalid syntax",
+			filename: "invalid.go",
+			src:      "package main\n\nfunc main() {\n\tif {\n\t}\n}\n",
+			wantErr:  true,
+		},
+	}
+
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			i, err := checker.CheckSource(tc.filename, []byte(tc.src))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, i)
+		})
+	}
+}
+
+func TestCheckDir(t *testing.T) {
+	cases := []struct {
+		name          string
+		dir           string
+		skipGenerated bool
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name: "generated files checked",
+			dir:  "./testdata/gen",
+			want: []string{"testdata/gen/gen.go:10:2", "testdata/gen/plain.go:9:2"},
+		},
+		{
+			name:          "generated files skipped",
+			dir:           "./testdata/gen",
+			skipGenerated: true,
+			want:          []string{"testdata/gen/plain.go:9:2"},
+		},
+		{
+			name: "no go files",
+			dir:  "./testdata/nogo",
+			want: []string{},
+		},
+		{
+			name:    "directory not found",
+			dir:     "./testdata/not-found",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				SkipGenerated: tc.skipGenerated,
+			}
+			issues, err := checker.CheckDir(tc.dir)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if tc.wantErr {
+				return
+			}
+			got := make([]string, 0, len(issues))
+			for _, i := range issues {
+				got = append(got, i.Pos.String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	for _, path := range []string{"./testdata/a.go", "./testdata/d.go"} {
+		src, _ := ioutil.ReadFile(path)
+		f, _ := parser.ParseFile(fset, path, src, parser.ParseComments)
+		files[path] = f
+	}
+	// The same file given twice must not be reported twice.
+	files["./testdata/a2.go"] = files["./testdata/a.go"]
+
+	checker := &Checker{
+		MinComplexity: 2,
+	}
+	want := []Issue{
+		{
+			Pos: token.Position{
+				Filename: "./testdata/d.go",
+				Offset:   152,
+				Line:     16,
+				Column:   2,
+			},
+			EndPos: token.Position{
+				Filename: "./testdata/d.go",
+				Offset:   221,
+				Line:     21,
+				Column:   3,
+			},
+			Complexity: 3,
+			Message:    "`if b1` has complex nested blocks (complexity: 3)",
+			Condition:  "b1",
+			Path:       "if > if > if",
+			RelLine:    13,
+			FuncName:   "_",
+			Severity:   "warning",
+			Breakdown:  ComplexityBreakdown{Nesting: 3},
+		},
+	}
+	assert.Equal(t, want, checker.CheckFiles(files, fset))
+
+	checker.MinComplexity = 1
+	assert.Len(t, checker.CheckFiles(files, fset), 4)
+}
+
+func TestCheckPaths(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 3,
+	}
+	issues, errs := checker.CheckPaths([]string{"./testdata/invalid.go", "./testdata/a.go", "./testdata/not-found.go", "./testdata/d.go"})
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Pos.String())
+	}
+	assert.Equal(t, []string{"./testdata/d.go:16:2"}, got)
+	assert.Len(t, errs, 2)
+	assert.Error(t, errs["./testdata/invalid.go"])
+	assert.Error(t, errs["./testdata/not-found.go"])
+
+	issues, errs = checker.CheckPaths([]string{"./testdata/a.go"})
+	assert.Empty(t, issues)
+	assert.Nil(t, errs)
+}
+
+func TestGroupByFile(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	var issues []Issue
+	for _, path := range []string{"./testdata/a.go", "./testdata/c.go", "./testdata/d.go"} {
+		i, err := checker.CheckFile(path)
+		assert.NoError(t, err)
+		issues = append(issues, i...)
+	}
+	// Group them regardless of the order found.
+	for i, j := 0, len(issues)-1; i < j; i, j = i+1, j-1 {
+		issues[i], issues[j] = issues[j], issues[i]
+	}
+
+	got := make(map[string][]int)
+	for name, group := range GroupByFile(issues) {
+		for _, issue := range group {
+			assert.Equal(t, name, issue.Pos.Filename)
+			got[name] = append(got[name], issue.Pos.Line)
+		}
+	}
+	want := map[string][]int{
+		"./testdata/a.go": {9},
+		"./testdata/c.go": {6, 14},
+		"./testdata/d.go": {6, 11, 16},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestCheckConcurrently(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	paths := []string{"./testdata/a.go", "./testdata/b.go", "./testdata/c.go", "./testdata/d.go"}
+	want := make(map[string][]Issue, len(paths))
+	for _, path := range paths {
+		i, err := checker.CheckFile(path)
+		assert.NoError(t, err)
+		want[path] = i
+	}
+
+	var wg sync.WaitGroup
+	got := make([][]Issue, len(paths))
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			got[i], _ = checker.CheckFile(path)
+		}(i, path)
+	}
+	wg.Wait()
+	for i, path := range paths {
+		assert.Equal(t, want[path], got[i])
+	}
+}
+
+func TestReset(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	want, err := checker.CheckFile("./testdata/k.go")
+	assert.NoError(t, err)
+
+	checker.Reset()
+	assert.Equal(t, 1, checker.MinComplexity)
+	got, err := checker.CheckFile("./testdata/k.go")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestCheckFuncs(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	filepath := "./testdata/k.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	issues, funcs := checker.CheckFuncs(f, fset)
+	assert.Equal(t, checker.Check(f, fset), issues)
+
+	want := []FuncComplexity{
+		{
+			Pos:           token.Position{Filename: filepath, Offset: 18, Line: 3, Column: 1},
+			Complexity:    3,
+			MaxComplexity: 3,
+		},
+		{
+			Pos:        token.Position{Filename: filepath, Offset: 142, Line: 14, Column: 1},
+			Complexity: 0,
+		},
+		{
+			Pos:           token.Position{Filename: filepath, Offset: 240, Line: 24, Column: 1},
+			Complexity:    2,
+			MaxComplexity: 1,
+		},
+	}
+	assert.Equal(t, want, funcs)
+}
+
+func TestSeverity(t *testing.T) {
+	cases := []struct {
+		name            string
+		warnComplexity  int
+		errorComplexity int
+		want            []string
+	}{
+		{
+			name: "warnings by default",
+			want: []string{"warning", "warning", "warning"},
+		},
+		{
+			name:            "all bands",
+			warnComplexity:  2,
+			errorComplexity: 3,
+			want:            []string{"info", "info", "error"},
+		},
+		{
+			name:           "no errors",
+			warnComplexity: 3,
+			want:           []string{"info", "info", "warning"},
+		},
+	}
+
+	filepath := "./testdata/d.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity:   1,
+				WarnComplexity:  tc.warnComplexity,
+				ErrorComplexity: tc.errorComplexity,
+			}
+			var got []string
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.Severity)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExportedOnly(t *testing.T) {
+	cases := []struct {
+		name         string
+		exportedOnly bool
+		want         []string
+	}{
+		{
+			name: "all functions",
+			want: []string{"Exported", "unexported", "t.Method", "t.method", "glob..func1"},
+		},
+		{
+			name:         "exported only",
+			exportedOnly: true,
+			want:         []string{"Exported", "t.Method"},
+		},
+	}
+
+	filepath := "./testdata/z.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				ExportedOnly:  tc.exportedOnly,
+			}
+			var got []string
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.FuncName)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestInvertible(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	filepath := "./testdata/p.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	var got []bool
+	for _, issue := range checker.Check(f, fset) {
+		got = append(got, issue.Invertible)
+	}
+	// Only the nested if ending with a return can be inverted; the else
+	// of the root if doesn't count.
+	assert.Equal(t, []bool{true, false}, got)
+}
+
+func TestMinLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		minLines int
+		want     []int
+	}{
+		{
+			name: "no line-based filtering",
+			want: []int{6, 8},
+		},
+		{
+			name:     "short if filtered",
+			minLines: 5,
+			want:     []int{8},
+		},
+		{
+			name:     "both filtered",
+			minLines: 7,
+			want:     nil,
+		},
+	}
+
+	filepath := "./testdata/lines.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				MinLines:      tc.minLines,
+			}
+			var got []int
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.Pos.Line)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBreakdownAddsUp(t *testing.T) {
+	checker := &Checker{
+		MinComplexity:      1,
+		CountNegations:     true,
+		IncludeSwitch:      true,
+		IncludeLoops:       true,
+		IncludeSelect:      true,
+		IncludeBooleanOps:  true,
+		ElseIfChainPenalty: 1,
+		InitClausePenalty:  1,
+		IncludeLabels:      true,
+	}
+	for _, path := range []string{"./testdata/c.go", "./testdata/h.go", "./testdata/q.go", "./testdata/u.go", "./testdata/x.go", "./testdata/y.go", "./testdata/labels.go"} {
+		issues, err := checker.CheckFile(path)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, issues, path)
+		for _, issue := range issues {
+			assert.Equal(t, issue.Complexity, issue.Breakdown.Total(), issue.Pos.String())
+		}
+	}
+}
+
+func TestIncludeLabels(t *testing.T) {
+	cases := []struct {
+		name          string
+		includeLabels bool
+		want          []ComplexityBreakdown
+	}{
+		{
+			name: "labels ignored",
+			want: []ComplexityBreakdown{{Nesting: 1}, {Nesting: 1}},
+		},
+		{
+			name:          "labels counted",
+			includeLabels: true,
+			want:          []ComplexityBreakdown{{Nesting: 1, Labels: 1}, {Nesting: 1, Labels: 2}},
+		},
+	}
+
+	filepath := "./testdata/labels.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				IncludeLabels: tc.includeLabels,
+			}
+			var got []ComplexityBreakdown
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.Breakdown)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestElseWeight(t *testing.T) {
+	weight := func(w int) *int { return &w }
+	cases := []struct {
+		name   string
+		weight *int
+		want   []int
+	}{
+		{
+			name: "default",
+			want: []int{4, 4},
+		},
+		{
+			name:   "free else",
+			weight: weight(0),
+			want:   []int{3, 4},
+		},
+		{
+			name:   "weight of 1",
+			weight: weight(1),
+			want:   []int{4, 4},
+		},
+		{
+			name:   "weight of 2",
+			weight: weight(2),
+			want:   []int{5, 4},
+		},
+	}
+
+	filepath := "./testdata/c.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				ElseWeight:    tc.weight,
+			}
+			var got []int
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.Complexity)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestInitClausePenalty(t *testing.T) {
+	cases := []struct {
+		name    string
+		penalty int
+		want    []int
+	}{
+		{
+			name: "no penalty",
+			want: []int{1, 1},
+		},
+		{
+			name:    "penalty of 1",
+			penalty: 1,
+			want:    []int{1, 3},
+		},
+	}
+
+	filepath := "./testdata/y.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity:     1,
+				InitClausePenalty: tc.penalty,
+			}
+			var got []int
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, issue.Complexity)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDepthWeight(t *testing.T) {
+	cases := []struct {
+		name   string
+		weight float64
+		want   int
+	}{
+		{
+			name:   "linear by default",
+			weight: 0,
+			want:   9,
+		},
+		{
+			name:   "linear",
+			weight: 1.0,
+			want:   9,
+		},
+		{
+			// 1 + 2^1.5 + 1 + 2^1.5 + 3^1.5, each rounded.
+			name:   "depth 3 weighted more",
+			weight: 1.5,
+			want:   13,
+		},
+	}
+	filepath := "./testdata/b.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity: 1,
+				DepthWeight:   tc.weight,
+			}
+			issues := checker.Check(f, fset)
+			assert.Len(t, issues, 1)
+			assert.Equal(t, tc.want, issues[0].Complexity)
+			assert.Equal(t, issues[0].Complexity, issues[0].Breakdown.Total())
+		})
+	}
+}
+
+func TestMessageFunc(t *testing.T) {
+	filepath := "./testdata/b.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	issues := checker.Check(f, fset)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, "`if b1` has complex nested blocks (complexity: 9)", issues[0].Message)
+
+	checker.MessageFunc = func(cond string, complexity int, pos token.Position) string {
+		return fmt.Sprintf("if %s at %d:%d is nested too deeply (%d)", cond, pos.Line, pos.Column, complexity)
+	}
+	issues = checker.Check(f, fset)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, "if b1 at 5:2 is nested too deeply (9)", issues[0].Message)
+}
+
+func TestFuncName(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	filepath := "./testdata/r.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	var got []string
+	for _, issue := range checker.Check(f, fset) {
+		got = append(got, issue.FuncName)
+	}
+	assert.Equal(t, []string{"S.M", "F.func1", "F.func1.1", "F.func2", "glob..func1", "glob..func2.1"}, got)
+}
+
+func TestCheckDecl(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	filepath := "./testdata/r.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	got := []Issue{}
+	for _, decl := range f.Decls {
+		got = append(got, checker.CheckDecl(f, decl, fset)...)
+	}
+	assert.Equal(t, checker.Check(f, fset), got)
+}
+
+func TestGuards(t *testing.T) {
+	checker := &Checker{
+		MinComplexity:     1,
+		InitClausePenalty: 1,
+		CountNegations:    true,
+		IncludeBooleanOps: true,
+		IncludeLoops:      true,
+		DepthWeight:       2,
+	}
+	filepath := "./testdata/guards.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	_, funcs := checker.CheckFuncs(f, fset)
+	assert.Len(t, funcs, 1)
+	assert.Equal(t, 3, funcs[0].Guards)
+}
+
+func TestClosureScope(t *testing.T) {
+	cases := []struct {
+		name         string
+		keepClosures bool
+		want         []string
+	}{
+		{
+			name: "closures checked on their own",
+			want: []string{"F.func1: 1", "G.func1: 1", "H.func1: 1"},
+		},
+		{
+			name:         "closures keep the nesting",
+			keepClosures: true,
+			want:         []string{"F.func1: 5", "G.func1: 3", "H: 3"},
+		},
+	}
+	filepath := "./testdata/closures.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &Checker{
+				MinComplexity:      1,
+				IncludeLoops:       true,
+				IncludeSelect:      true,
+				KeepClosureNesting: tc.keepClosures,
+			}
+			var got []string
+			for _, issue := range checker.Check(f, fset) {
+				got = append(got, fmt.Sprintf("%s: %d", issue.FuncName, issue.Complexity))
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCheckContext(t *testing.T) {
+	checker := &Checker{
+		MinComplexity: 1,
+	}
+	filepath := "./testdata/r.go"
+	src, _ := ioutil.ReadFile(filepath)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filepath, src, parser.ParseComments)
+
+	got, err := checker.CheckContext(context.Background(), f, fset)
+	assert.NoError(t, err)
+	assert.Equal(t, checker.Check(f, fset), got)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, err = checker.CheckContext(ctx, f, fset)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, got)
 }
 
 func TestDebug(t *testing.T) {
@@ -0,0 +1,32 @@
+package changed
+
+// A comment moving everything down.
+
+func unchanged() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
+
+func changed() {
+	var b1, b2, b3 bool
+
+	if b1 { // complexity: 3
+		if b2 { // +1
+			if b3 { // +2
+			}
+		}
+	}
+}
+
+func added() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package changed
+
+func unchanged() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
+
+func changed() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package testdata
+
+func F() {
+	var b1, b2 bool
+
+	for {
+		for {
+			go func() {
+				if b1 { // F.func1, complexity: 1, or 5 keeping the nesting in closures
+					if b2 {
+					}
+				}
+			}()
+		}
+	}
+}
+
+func G(ch chan int) {
+	var b1, b2 bool
+
+	select {
+	case <-ch:
+		go func() {
+			if b1 { // G.func1, complexity: 1, or 3
+				if b2 {
+				}
+			}
+		}()
+	}
+}
+
+func H() {
+	var b1, b2, b3 bool
+
+	if b1 { // complexity: 0, or 3 with the closure in it
+		f := func() {
+			if b2 { // H.func1, complexity: 1
+				if b3 {
+				}
+			}
+		}
+		f()
+	}
+}
@@ -0,0 +1,11 @@
+package testdata
+
+func _() {
+	var n int
+	var b1 bool
+
+	if n < 10 && b1 { // complexity: 1
+		if b1 { // +1
+		}
+	}
+}
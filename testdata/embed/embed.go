@@ -0,0 +1,18 @@
+package embed
+
+import (
+	_ "embed"
+)
+
+//go:embed hello.txt
+var hello string
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+			_ = hello
+		}
+	}
+}
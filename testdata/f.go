@@ -0,0 +1,13 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	var s []int
+
+	if b1 { // complexity: 1
+		for range s {
+			if b2 { // +1
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package testdata
+
+func _() {
+	var a, b int
+	var x bool
+
+	if !(a == b) { // complexity: 1, +1 for negation
+		if !!x { // +1, +1 for double negation
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package testdata
+
+func _() {
+	f := func() bool { return true }
+
+	if ok := f(); ok { // guard, with a penalty for the init clause
+		return
+	}
+
+	if !f() { // guard, counting negations
+		return
+	}
+
+	for {
+		if f() && f() || f() { // guard, counting boolean operators
+			break
+		}
+	}
+
+	if f() { // not a guard
+		if f() {
+		}
+		return
+	}
+}
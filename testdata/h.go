@@ -0,0 +1,16 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if f := func() bool { // complexity: 1
+		if b1 { // not counted
+			if b2 { // not counted
+			}
+		}
+		return b1
+	}; f() {
+		if b2 { // +1
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package testdata
+
+func _() {
+	var b1, b2, b3, b4 bool
+
+	if b1 { // complexity: 4, or 1 when ignoring empty bodies
+		if b2 { // +1, or 0
+		}
+		if b3 { // +1
+			if b4 { // +2, or 0
+			}
+		}
+	}
+}
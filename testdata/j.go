@@ -0,0 +1,23 @@
+package testdata
+
+type T struct{ b bool }
+
+func (t *T) Method() bool {
+	if t.b { // complexity: 1
+		if !t.b { // +1
+		}
+	}
+	return t.b
+}
+
+var f = (&T{}).Method
+
+func _() {
+	g := (&T{}).Method
+	s := struct{ m func() bool }{m: (&T{}).Method}
+
+	if g() { // complexity: 1
+		if f() && (*T).Method(&T{}) && s.m() { // +1
+		}
+	}
+}
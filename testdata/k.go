@@ -0,0 +1,36 @@
+package testdata
+
+func _() { // complexity: 3
+	var b1, b2, b3 bool
+
+	if b1 { // complexity: 3
+		if b2 { // +1
+			if b3 { // +2
+			}
+		}
+	}
+}
+
+func _() { // complexity: 0
+	var b1 bool
+
+	if b1 { // complexity: 0
+	}
+}
+
+func _() { // no ifs
+}
+
+func _() { // complexity: 2
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	var n int
+	var i interface{}
+
+	if b1 { // complexity: 1, or 3 with switch
+		switch n { // +1
+		case 1:
+			if b2 { // +2
+			}
+		}
+	}
+
+	if b1 { // complexity: 0, or 1 with switch
+		switch i.(type) { // +1
+		case int:
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1, or 2 with labels
+		if b2 { // +1
+			goto done // +1 with labels
+		}
+	}
+
+	if b1 { // complexity: 1, or 3 with labels
+	outer: // +1 with labels
+		for {
+			if b2 { // +1
+				break outer // +1 with labels
+			}
+		}
+	}
+
+done:
+}
@@ -0,0 +1,14 @@
+package testdata
+
+func _() {
+	var b1, b2, b3 bool
+
+	if b1 { if b2 { if b3 { } } } // complexity: 3, span: 0 lines
+
+	if b1 { // complexity: 3, span: 6 lines
+		if b2 { // +1
+			if b3 { // +2
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package testdata
+
+type featureFlags struct{ enabled bool }
+
+func _() {
+	var flags featureFlags
+	var b1 bool
+
+	if flags.enabled { // complexity: 1
+		if b1 { // +1
+		}
+	}
+
+	if b1 { // complexity: 1
+		if b1 { // +1
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { //nolint:nestif
+		if b2 {
+		}
+	}
+
+	//nolint
+	if b1 {
+		if b2 {
+		}
+	}
+
+	if b1 { //nolint:gocyclo,nestif // explanation
+		if b2 {
+		}
+	}
+
+	if b1 { //nolint:gocyclo
+		if b2 { //nolint:nestif
+		}
+	}
+}
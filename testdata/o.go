@@ -0,0 +1,11 @@
+package testdata
+
+func _() {
+	var a, b func() error
+
+	if err := a(); err != nil { // complexity: 1
+		if err := b(); err != nil { // +1
+			return
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // guard
+		return
+	}
+
+	if b2 { // guard
+		panic("b2")
+	}
+
+	for {
+		if b1 { // guard
+			break
+		}
+	}
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+			return
+		}
+	}
+
+	if b1 { // not a guard
+	} else {
+		return
+	}
+}
@@ -0,0 +1,23 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	var s []int
+
+	for range s {
+		for range s {
+			if b1 { // complexity: 2, or 6 with loops
+				if b2 { // +1, or +3
+				}
+			} else { // +1
+			}
+		}
+	}
+
+	if b1 { // complexity: 1, or 3 with loops
+		for range s { // +0, or +1
+			if b2 { // +1, or +2
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package testdata
+
+type S struct{}
+
+func (S) M() {
+	var b1, b2 bool
+
+	if b1 { // S.M
+		if b2 {
+		}
+	}
+}
+
+func F() {
+	var b1, b2 bool
+
+	_ = func() {
+		if b1 { // F.func1
+			if b2 {
+			}
+		}
+		_ = func() {
+			if b1 { // F.func1.1
+				if b2 {
+				}
+			}
+		}
+	}
+	_ = func() {
+		if b1 { // F.func2
+			if b2 {
+			}
+		}
+	}
+}
+
+var handler = func() {
+	var b1, b2 bool
+
+	if b1 { // glob..func1
+		if b2 {
+		}
+	}
+}
+
+var (
+	_ = func() {
+		_ = func() {
+			var b1, b2 bool
+
+			if b1 { // glob..func2.1
+				if b2 {
+				}
+			}
+		}
+	}
+)
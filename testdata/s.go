@@ -0,0 +1,32 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	var s []int
+
+	for range s {
+		if b1 { // complexity: 1
+			if b2 { // +1
+			}
+		}
+		if b2 { // complexity: 1
+			if b1 { // +1
+			}
+		}
+	}
+
+	switch {
+	case b1:
+		if b1 { // complexity: 1
+			if b2 { // +1
+			}
+		}
+	default:
+		for {
+			if b2 { // complexity: 1
+				if b1 { // +1
+				}
+			}
+		}
+	}
+}
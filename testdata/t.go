@@ -0,0 +1,14 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 0, or 3 keeping the nesting in closures
+		_ = func() {
+			if b2 { // _.func1, complexity: 1, or +1
+				if b1 { // +1, or +2
+				}
+			}
+		}
+	}
+}
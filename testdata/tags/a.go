@@ -0,0 +1,10 @@
+package tags
+
+func _() {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package a
+
+import "github.com/nakabonne/nestif/testdata/topo/b"
+
+func _() {
+	var b1, b2, b3 bool
+
+	if b.B() { // complexity: 3
+		if b2 { // +1
+			if b3 { // +2
+			}
+		}
+	}
+	_ = b1
+}
@@ -0,0 +1,11 @@
+package b
+
+func B() bool {
+	var b1, b2 bool
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+	return b1
+}
@@ -0,0 +1,13 @@
+package testdata
+
+func _() {
+	var a, b, c, d bool
+
+	if a && b && c || d { // complexity: 2 counting boolean operators
+	}
+
+	if a { // complexity: 1, or 4 counting boolean operators
+		if a && (b || c) && d { // +1, +3
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package testdata
+
+func _() {
+	var b1 bool
+	var f func() error
+	err := f()
+
+	if err != nil { // not counted, or complexity: 1 with IfErr
+		if b1 { // +1
+		}
+	}
+
+	if err == nil { // complexity: 1
+		if b1 { // +1
+		}
+	}
+}
+
+func _() {
+	var b1 bool
+	var err *int
+
+	if err != nil { // complexity: 1, as err isn't an error
+		if b1 { // +1
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	var ch chan int
+
+	for {
+		select {
+		case <-ch:
+			if b1 { // complexity: 1, 3 counting select, 5 counting loops too
+				if b2 { // +1, +2, +3
+				}
+			}
+		}
+	}
+
+	if b1 { // complexity: 1, or 3 counting select
+		select { // +0, or +1
+		case <-ch:
+			if b2 { // +1, or +2
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package testdata
+
+func _() {
+	var b1, b2, b3, b4, b5 bool
+
+	if b1 { // complexity: 5, or 7 with a penalty of 1 beyond 2 else ifs
+	} else if b2 { // +1
+	} else if b3 { // +1
+	} else if b4 { // +1, +1
+	} else if b5 { // +1, +1
+	} else { // +1
+	}
+}
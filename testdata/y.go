@@ -0,0 +1,16 @@
+package testdata
+
+func _() {
+	var b1, b2 bool
+	f := func() bool { return true }
+
+	if b1 { // complexity: 1
+		if b2 { // +1
+		}
+	}
+
+	if ok := f(); ok { // complexity: 1, or 3 with a penalty of 1
+		if ok := f(); ok { // +1, +1
+		}
+	}
+}
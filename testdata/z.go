@@ -0,0 +1,38 @@
+package testdata
+
+type t struct{}
+
+func Exported(b1, b2 bool) {
+	if b1 { // checked
+		if b2 {
+		}
+	}
+}
+
+func unexported(b1, b2 bool) {
+	if b1 { // skipped
+		if b2 {
+		}
+	}
+}
+
+func (t) Method(b1, b2 bool) {
+	if b1 { // checked, by the name regardless of the receiver
+		if b2 {
+		}
+	}
+}
+
+func (t) method(b1, b2 bool) {
+	if b1 { // skipped
+		if b2 {
+		}
+	}
+}
+
+var closure = func(b1, b2 bool) {
+	if b1 { // skipped
+		if b2 {
+		}
+	}
+}